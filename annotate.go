@@ -0,0 +1,200 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Annotated wraps a constructor or target pointer together with registration options.
+// It is accepted by Add wherever a bare constructor or pointer is.
+type Annotated struct {
+	Target  interface{}
+	Options []Option
+}
+
+// Option customizes how an annotated constructor or target is registered.
+type Option func(*options)
+
+type options struct {
+	name      string
+	group     string
+	optional  bool
+	paramTags []string
+	as        []reflect.Type
+	private   bool
+	shadow    bool
+}
+
+func (o *options) isZero() bool {
+	return o.name == "" && o.group == "" && !o.optional && len(o.paramTags) == 0 && len(o.as) == 0 && !o.private && !o.shadow
+}
+
+// Annotate attaches options to a constructor or target pointer.
+//
+//	b.Add(
+//		bootstrap.Annotate(NewPrimaryDB, bootstrap.Name("primary")),
+//		bootstrap.Annotate(NewReplicaDB, bootstrap.Name("replica")),
+//		bootstrap.Annotate(NewRepository, bootstrap.ParamTags(`name:"primary"`, `name:"replica"`)),
+//	)
+func Annotate(target interface{}, opts ...Option) Annotated {
+	return Annotated{Target: target, Options: opts}
+}
+
+// Name registers every output of a constructor under the given name.
+// When applied to a target pointer it selects the named value to populate it with.
+func Name(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Group adds every output of a constructor to the named value group.
+// Consumers receive all members as a slice, ordered by registration.
+// When applied to a target pointer of slice type it populates it with the group.
+func Group(name string) Option {
+	return func(o *options) {
+		o.group = name
+	}
+}
+
+// Optional marks a target pointer as optional: it is left at its zero value
+// when no provider exists instead of failing with a missing dependency.
+func Optional() Option {
+	return func(o *options) {
+		o.optional = true
+	}
+}
+
+// Private restricts the outputs of a constructor registered by a Module to the providers
+// of the same module. They do not collide with values of the same type elsewhere, and
+// take precedence over them within the module. Private value groups are not supported.
+//
+//	bootstrap.Module{
+//		Name:      "cache",
+//		Providers: []interface{}{bootstrap.Annotate(newRedisConfig, bootstrap.Private()), NewClient},
+//	}
+func Private() Option {
+	return func(o *options) {
+		o.private = true
+	}
+}
+
+// Shadow allows a constructor or supplied value of a child container to take the place
+// of a value the parent provides. Without it, providing the same key as the parent is
+// reported as a DuplicateProviderError. It has no effect outside child containers.
+func Shadow() Option {
+	return func(o *options) {
+		o.shadow = true
+	}
+}
+
+// As additionally registers the constructor output implementing interface T under T.
+// The same instance is shared by the concrete and the interface key; name and group
+// options apply to both. Use it several times to bind more than one interface.
+//
+//	b.Add(bootstrap.Annotate(NewPostgresStore, bootstrap.As[Store]()))
+func As[T any]() Option {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return func(o *options) {
+		o.as = append(o.as, t)
+	}
+}
+
+// ParamTags attaches struct-tag style annotations to the parameters of a constructor,
+// in positional order. An empty string leaves the corresponding parameter unchanged.
+//
+// Supported keys:
+//   - name:"x" requests the value registered under name x.
+//   - group:"x" requests all members of value group x; the parameter must be a slice.
+//   - optional:"true" injects the zero value when no provider exists.
+func ParamTags(tags ...string) Option {
+	return func(o *options) {
+		o.paramTags = tags
+	}
+}
+
+// tagInfo holds the dependency annotations parsed from a struct tag.
+type tagInfo struct {
+	name     string
+	group    string
+	optional bool
+}
+
+func parseTag(tag reflect.StructTag) (tagInfo, error) {
+	info := tagInfo{
+		name:  tag.Get("name"),
+		group: tag.Get("group"),
+	}
+	if info.name != "" && info.group != "" {
+		return tagInfo{}, fmt.Errorf("tag %q cannot specify both name and group", tag)
+	}
+	if v, ok := tag.Lookup("optional"); ok {
+		optional, err := strconv.ParseBool(v)
+		if err != nil {
+			return tagInfo{}, fmt.Errorf("tag %q has invalid optional value: %w", tag, err)
+		}
+		info.optional = optional
+	}
+	return info, nil
+}
+
+// input returns the graph input for a dependency of type t annotated with info.
+func (info tagInfo) input(t reflect.Type) dag.Input {
+	return dag.Input{
+		Key:      dag.Key{Type: t, Name: info.name, Group: info.group},
+		Optional: info.optional,
+	}
+}
+
+func (o *options) apply(args []Option) {
+	for _, opt := range args {
+		opt(o)
+	}
+}
+
+func (o *options) validate() error {
+	if o.name != "" && o.group != "" {
+		return fmt.Errorf("name %q and group %q are mutually exclusive", o.name, o.group)
+	}
+	return nil
+}
+
+func checkParamTags(tags []string, typ reflect.Type) error {
+	if len(tags) > typ.NumIn() {
+		return fmt.Errorf("got %d parameter tags for %d parameters of %v", len(tags), typ.NumIn(), typ)
+	}
+	return nil
+}
+
+// bindInterfaces adds an output for every interface requested with As,
+// sharing the return value of the single output that implements it.
+func bindInterfaces(p *dag.Node, opts *options) error {
+	outputs := p.Outputs
+	for _, iface := range opts.as {
+		if iface.Kind() != reflect.Interface {
+			return fmt.Errorf("cannot bind %v: not an interface type", iface)
+		}
+
+		var bound *dag.Output
+		for i := range outputs {
+			if !outputs[i].Type.Implements(iface) {
+				continue
+			}
+			if bound != nil {
+				return fmt.Errorf("cannot bind %v: implemented by both %v and %v", iface, bound.Type, outputs[i].Type)
+			}
+			bound = &outputs[i]
+		}
+		if bound == nil {
+			return fmt.Errorf("cannot bind %v: no output of %v implements it", iface, p.Fn.Type())
+		}
+
+		out := *bound
+		out.Type = iface
+		p.Outputs = append(p.Outputs, out)
+	}
+	return nil
+}
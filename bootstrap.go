@@ -2,9 +2,13 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/viilon/bootstrap/dag"
 )
@@ -12,32 +16,72 @@ import (
 // Bootstrap manages the bootstrap process with dependency injection and topological execution.
 type Bootstrap struct {
 	providers []*dag.Node
-	values    map[reflect.Type]reflect.Value
-	cleanups  []func() error
+	values    map[dag.Key]reflect.Value
+	groups    map[dag.Key]map[*dag.Node][]reflect.Value // Group members keyed by element key and producer
+	cleanups  []cleanup
 	functions map[uintptr]bool // Cache for registered functions to avoid duplicates
 	ctx       context.Context
 	cancel    context.CancelFunc
+	runCtx    context.Context    // Context injected by the current or last Run, derived from ctx
+	cancelRun context.CancelFunc // Cancels runCtx when the run fails
 	mu        sync.RWMutex
 	err       error // Store the first error encountered during Add
+	workers   int   // Maximum number of constructors running at once; <= 1 runs sequentially
+	lifecycle *lifecycle
+	overrides []*override         // Replacements registered with Replace
+	replaced  map[*dag.Node][]int // Result indices of providers taken over by a replacement
+	modules   map[string]Module   // Modules included so far, by name
+	module    []string            // Path of the module being registered, innermost first
+
+	parent    *Bootstrap         // Container whose values are inherited, or nil
+	children  []*Bootstrap       // Containers created by Child, shut down before this one
+	shadowing map[*dag.Node]bool // Providers allowed to shadow values of the parent
+	inherited map[*dag.Node]bool // Synthetic providers of parent values used by the last Run
+	ran       []*dag.Node        // Graph nodes of the last Run
+	runErr    error              // Error of the last Run, highlighted by DOT and Mermaid
+
+	noAutoCleanup bool           // Disables detection of conventional cleanup methods entirely
+	skipCleanup   []reflect.Type // Types excluded from detection of conventional cleanup methods
 }
 
 // New creates a new Bootstrap.
 func New() *Bootstrap {
-	ctx, cancel := context.WithCancel(context.Background())
+	return newBootstrap(context.Background())
+}
+
+func newBootstrap(parent context.Context) *Bootstrap {
+	ctx, cancel := context.WithCancel(parent)
 	r := &Bootstrap{
 		providers: make([]*dag.Node, 0),
-		values:    make(map[reflect.Type]reflect.Value),
-		cleanups:  make([]func() error, 0),
+		values:    make(map[dag.Key]reflect.Value),
+		groups:    make(map[dag.Key]map[*dag.Node][]reflect.Value),
+		cleanups:  make([]cleanup, 0),
 		functions: make(map[uintptr]bool),
+		modules:   make(map[string]Module),
+		replaced:  make(map[*dag.Node][]int),
+		shadowing: make(map[*dag.Node]bool),
 		ctx:       ctx,
 		cancel:    cancel,
+		lifecycle: newLifecycle(),
 	}
 
 	// Register default context provider
 	r.Add(func() context.Context {
-		return r.ctx
+		return r.runCtx
+	})
+
+	// Register default lifecycle provider
+	r.Add(func() Lifecycle {
+		return r.lifecycle
 	})
 
+	// Every container has its own context and lifecycle, even a child
+	for _, p := range r.providers {
+		p.Label = fmt.Sprintf("default %v", p.Outputs[0].Type)
+		p.Synthetic = true
+		r.shadowing[p] = true
+	}
+
 	return r
 }
 
@@ -55,7 +99,49 @@ func (b *Bootstrap) WithContext(ctx context.Context) *Bootstrap {
 	return b
 }
 
-// Add registers one or more constructors.
+// WithConcurrency makes Run execute independent constructors in parallel, with at most
+// workers of them running at once. Each constructor starts as soon as all of its inputs
+// have been built. When one fails, the context of the run is canceled, no further
+// constructors are started, and Run returns after the running ones have finished.
+// A value of 0 or 1 restores sequential execution.
+func (b *Bootstrap) WithConcurrency(workers int) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.workers = workers
+	return b
+}
+
+// WithTimeouts sets the time each start and stop hook may take. The stop timeout
+// also bounds each cleanup. Defaults are DefaultStartTimeout and DefaultStopTimeout.
+func (b *Bootstrap) WithTimeouts(start, stop time.Duration) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lifecycle.startTimeout = start
+	b.lifecycle.stopTimeout = stop
+	return b
+}
+
+// WithoutAutoCleanup disables the detection of conventional cleanup methods
+// (Shutdown(ctx) error, io.Closer, Close() and Stop()) for the given types, or for
+// every type when called without arguments. An interface type excludes every type
+// implementing it. Components implementing Cleanable or ContextCleanable are always
+// cleaned up.
+//
+//	b.WithoutAutoCleanup(reflect.TypeOf((*os.File)(nil)))
+func (b *Bootstrap) WithoutAutoCleanup(types ...reflect.Type) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(types) == 0 {
+		b.noAutoCleanup = true
+	}
+	b.skipCleanup = append(b.skipCleanup, types...)
+	return b
+}
+
+// Add registers one or more constructors, targets, struct injectors or modules.
 func (b *Bootstrap) Add(constructors ...interface{}) *Bootstrap {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -66,13 +152,24 @@ func (b *Bootstrap) Add(constructors ...interface{}) *Bootstrap {
 
 	for _, c := range constructors {
 		if err := b.add(c); err != nil {
-			b.err = err
+			b.err = &RegistrationError{Arg: c, Err: err}
 			return b
 		}
 	}
 	return b
 }
 
+// fail records err as the registration error unless one is already stored.
+func (b *Bootstrap) fail(err error) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
 // Run executes all registered constructors in topological order.
 func (b *Bootstrap) Run() error {
 	b.mu.Lock()
@@ -81,49 +178,194 @@ func (b *Bootstrap) Run() error {
 	if b.err != nil {
 		return b.err
 	}
+	if err := b.checkOverrides(); err != nil {
+		return err
+	}
+	nodes, inherited, err := b.graphNodes()
+	if err != nil {
+		b.runErr = err
+		return err
+	}
+	b.inherited = inherited
+	b.ran = nodes
 
-	sorted, err := dag.Resolve(b.providers)
+	g, err := dag.Build(nodes)
 	if err != nil {
+		b.runErr = err
 		return err
 	}
 
-	// Execute
-	for _, p := range sorted {
+	// Execute. If a constructor fails, the context of the run is canceled and
+	// every component built so far is cleaned up in reverse order and discarded
+	// before returning. The bootstrap context stays usable for a retry.
+	before := b.snapshot()
+	b.runCtx, b.cancelRun = context.WithCancel(b.ctx)
+	if b.workers > 1 {
+		err = b.executeConcurrently(g)
+	} else {
+		err = b.executeSequentially(g)
+	}
+	b.runErr = err
+	if err != nil {
+		b.cancelRun()
+		return errors.Join(err, b.rollback(before))
+	}
+
+	return nil
+}
+
+func (b *Bootstrap) executeSequentially(g *dag.Graph) error {
+	for _, p := range g.Nodes {
 		if err := b.execute(p); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
+// snapshot records what the container holds before a run.
+type snapshot struct {
+	values   map[dag.Key]reflect.Value
+	members  map[dag.Key]map[*dag.Node]int // Number of group members per producer
+	cleanups int
+	hooks    int
+}
+
+func (b *Bootstrap) snapshot() snapshot {
+	s := snapshot{
+		values:   maps.Clone(b.values),
+		members:  make(map[dag.Key]map[*dag.Node]int, len(b.groups)),
+		cleanups: len(b.cleanups),
+		hooks:    b.lifecycle.len(),
+	}
+	for key, members := range b.groups {
+		counts := make(map[*dag.Node]int, len(members))
+		for p, vs := range members {
+			counts[p] = len(vs)
+		}
+		s.members[key] = counts
+	}
+	return s
+}
+
+// rollback restores the container to the snapshot taken before a failed run: the
+// values, group members and lifecycle hooks produced by the run are discarded, and
+// its cleanups are run in reverse order. The context of the run is canceled, so a
+// fresh one is used.
+func (b *Bootstrap) rollback(s snapshot) error {
+	b.values = s.values
+	for key, members := range b.groups {
+		for p, vs := range members {
+			if n := s.members[key][p]; n > 0 {
+				members[p] = vs[:n]
+			} else {
+				delete(members, p)
+			}
+		}
+		if len(members) == 0 {
+			delete(b.groups, key)
+		}
+	}
+	b.lifecycle.truncate(s.hooks)
+
+	cleanups := b.cleanups[s.cleanups:]
+	b.cleanups = b.cleanups[:s.cleanups]
+	return runCleanups(context.Background(), b.lifecycle.stopTimeout, cleanups)
+}
+
+// Validate checks the registered graph the same way Run does, without invoking any
+// constructor or populating any target. Registration errors are returned as is;
+// otherwise every missing dependency, duplicate provider and cycle is reported at once
+// (see dag.Validate). A nil result means Run can execute every provider.
+func (b *Bootstrap) Validate() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.checkOverrides(); err != nil {
+		return err
+	}
+	nodes, _, err := b.graphNodes()
+	if err != nil {
+		return err
+	}
+	return dag.Validate(nodes)
+}
+
+// Start runs the start hooks registered through Lifecycle and the Start method of
+// every Startable component, in dependency order, after Run has constructed everything.
+// Each hook is bounded by the start timeout. If one fails, the hooks already started
+// are stopped in reverse order and the error is returned.
+func (b *Bootstrap) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lifecycle.start(ctx)
+}
+
+// Stop runs the stop hooks of everything started by Start in reverse order, each bounded
+// by the stop timeout. A failing hook does not prevent the remaining ones from running.
+func (b *Bootstrap) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lifecycle.stop(ctx)
+}
+
 // Cleanup gracefully shuts down the runner by calling registered cleanups in reverse order.
+// It is equivalent to Shutdown with a background context.
 func (b *Bootstrap) Cleanup() error {
+	return b.Shutdown(context.Background())
+}
+
+// Shutdown stops components still started, cancels the bootstrap context and calls the
+// registered cleanups in reverse order. Each stop hook and cleanup receives a context
+// bound by ctx and the stop timeout. A cleanup that does not finish in time is reported
+// with a CleanupError wrapping the context error and left running in the background,
+// so one hung component cannot block the others. Once ctx itself is done, the remaining
+// cleanups are still started but no longer waited for.
+func (b *Bootstrap) Shutdown(ctx context.Context) error {
+	childErr := b.shutdownChildren(ctx)
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	stopErr := b.lifecycle.stop(ctx)
+
 	// Cancel the context first
 	b.cancel()
 
-	var errs []error
-	// Execute cleanups in reverse order
-	for i := len(b.cleanups) - 1; i >= 0; i-- {
-		if err := b.cleanups[i](); err != nil {
-			errs = append(errs, err)
+	cleanups := b.cleanups
+	b.cleanups = b.cleanups[:0:0]
+	return errors.Join(childErr, stopErr, runCleanups(ctx, b.lifecycle.stopTimeout, cleanups))
+}
+func (b *Bootstrap) add(fn interface{}) error {
+	var opts options
+	if a, ok := fn.(Annotated); ok {
+		opts.apply(a.Options)
+		if err := opts.validate(); err != nil {
+			return err
 		}
+		fn = a.Target
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("cleanup errors: %v", errs)
+	if m, ok := fn.(*Module); ok && m != nil {
+		fn = *m
 	}
-	return nil
-}
-func (b *Bootstrap) add(fn interface{}) error {
+	if m, ok := fn.(Module); ok {
+		if !opts.isZero() {
+			return fmt.Errorf("options are not supported for module %s", m.Name)
+		}
+		return b.addModule(m)
+	}
+
 	val := reflect.ValueOf(fn)
 	typ := val.Type()
 
 	if typ.Kind() == reflect.Func {
-		return b.registerProvider(fn)
+		return b.registerProvider(fn, &opts)
 	}
 
 	if typ.Kind() == reflect.Ptr {
@@ -136,47 +378,124 @@ func (b *Bootstrap) add(fn interface{}) error {
 		// Case 1: Struct Injection (must embed bootstrap.Inject)
 		if elemType.Kind() == reflect.Struct {
 			if hasInject(elemType) {
+				if !opts.isZero() {
+					return fmt.Errorf("options are not supported for struct injection of %v; use field tags instead", typ)
+				}
 				return b.registerStructInjector(val)
 			}
 			// If not embedded Inject, fall through to Target Population
 		}
 
 		// Case 2: Target Population (pointer to pointer or interface, OR struct without Inject)
-		return b.registerTargetPopulator(val)
+		if len(opts.paramTags) > 0 || len(opts.as) > 0 || opts.private || opts.shadow {
+			return fmt.Errorf("parameter tags, interface bindings, Private and Shadow are not supported for target %v", typ)
+		}
+		return b.registerTargetPopulator(val, &opts)
 	}
 
 	return fmt.Errorf("argument must be a function or pointer")
 }
 
-func (b *Bootstrap) registerProvider(fn interface{}) error {
+func (b *Bootstrap) registerProvider(fn interface{}, opts *options) error {
 	val := reflect.ValueOf(fn)
-	typ := val.Type()
 
-	// Check inputs for embedded Inject
-	if err := checkInjectInTypes(typ.NumIn(), typ.In, "input"); err != nil {
-		return err
+	if opts.optional {
+		return fmt.Errorf("option Optional is only supported for targets; use an optional parameter tag for %v", val.Type())
 	}
 
-	// Check outputs for embedded Inject
-	if err := checkInjectInTypes(typ.NumOut(), typ.Out, "output"); err != nil {
+	p, err := providerNode(val, opts)
+	if err != nil {
 		return err
 	}
+	if opts.private {
+		if err := b.makePrivate(p); err != nil {
+			return err
+		}
+	}
+	if opts.shadow {
+		b.shadowing[p] = true
+	}
+
+	// Annotated constructors may legitimately be registered several times
+	// with different options, so only bare functions are deduplicated.
+	if opts.isZero() {
+		ptr := val.Pointer()
+		if b.functions[ptr] {
+			return nil // Already registered
+		}
+		b.functions[ptr] = true
+	}
+
+	b.provide(p)
+	return nil
+}
+
+// providerNode builds the graph node for the constructor val, expanding its parameter
+// and result objects and applying opts to its inputs and outputs.
+func providerNode(val reflect.Value, opts *options) (*dag.Node, error) {
+	typ := val.Type()
 
-	ptr := val.Pointer()
-	if b.functions[ptr] {
-		return nil // Already registered
+	if err := checkParamTags(opts.paramTags, typ); err != nil {
+		return nil, err
 	}
-	b.functions[ptr] = true
 
-	p, err := dag.NewNode(fn)
+	// Expand parameter and result objects into their fields
+	call, inputs, err := expandParams(val, opts.paramTags)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	b.providers = append(b.providers, p)
-	return nil
+	call, resultTags, err := expandResults(call)
+	if err != nil {
+		return nil, err
+	}
+	if resultTags != nil && (opts.name != "" || opts.group != "") {
+		return nil, fmt.Errorf("name and group options are not supported for result objects returned by %v; tag their fields instead", typ)
+	}
+
+	// Check inputs for embedded Inject
+	inputType := func(i int) reflect.Type { return inputs[i].Type }
+	if err := checkInjectInTypes(len(inputs), inputType, "input"); err != nil {
+		return nil, err
+	}
+
+	// Check outputs for embedded Inject
+	if err := checkInjectInTypes(call.Type().NumOut(), call.Type().Out, "output"); err != nil {
+		return nil, err
+	}
+
+	p, err := dag.NewNode(call.Interface())
+	if err != nil {
+		return nil, err
+	}
+	p.Inputs = inputs
+	if _, ok := resultTags[p.CleanupIndex]; ok {
+		// A function-typed field of a result object is a value, not a cleanup hook.
+		p.Outputs = append(p.Outputs, dag.Output{
+			Key:   dag.Key{Type: call.Type().Out(p.CleanupIndex)},
+			Index: p.CleanupIndex,
+		})
+		p.CleanupIndex = -1
+	}
+	if call != val {
+		p.Label = dag.FuncLabel(val)
+		p.File, p.Line = dag.FuncLocation(val)
+	}
+	for i := range p.Outputs {
+		if info, ok := resultTags[p.Outputs[i].Index]; ok {
+			p.Outputs[i].Name = info.name
+			p.Outputs[i].Group = info.group
+			continue
+		}
+		p.Outputs[i].Name = opts.name
+		p.Outputs[i].Group = opts.group
+	}
+	if err := bindInterfaces(p, opts); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-func (b *Bootstrap) registerTargetPopulator(ptrVal reflect.Value) error {
+func (b *Bootstrap) registerTargetPopulator(ptrVal reflect.Value, opts *options) error {
 	// ptrVal is *T. We want to set it to a value of type T.
 	targetType := ptrVal.Type().Elem()
 
@@ -199,7 +518,12 @@ func (b *Bootstrap) registerTargetPopulator(ptrVal reflect.Value) error {
 	if err != nil {
 		return err
 	}
-	b.providers = append(b.providers, p)
+	p.Label = fmt.Sprintf("populate %v", ptrVal.Type())
+	p.Synthetic = true
+	p.Inputs[0].Name = opts.name
+	p.Inputs[0].Group = opts.group
+	p.Inputs[0].Optional = opts.optional
+	b.appendNode(p)
 	return nil
 }
 
@@ -209,6 +533,7 @@ func (b *Bootstrap) registerStructInjector(structPtrVal reflect.Value) error {
 
 	var fieldTypes []reflect.Type
 	var fieldIndices []int
+	var fieldTags []tagInfo
 
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
@@ -221,8 +546,14 @@ func (b *Bootstrap) registerStructInjector(structPtrVal reflect.Value) error {
 			continue
 		}
 
+		info, err := parseTag(field.Tag)
+		if err != nil {
+			return fmt.Errorf("field %s of %v: %w", field.Name, structType, err)
+		}
+
 		fieldTypes = append(fieldTypes, field.Type)
 		fieldIndices = append(fieldIndices, i)
+		fieldTags = append(fieldTags, info)
 	}
 
 	// Create synthetic function: func(f1 T1, f2 T2, ...)
@@ -239,7 +570,12 @@ func (b *Bootstrap) registerStructInjector(structPtrVal reflect.Value) error {
 	if err != nil {
 		return err
 	}
-	b.providers = append(b.providers, p)
+	p.Label = fmt.Sprintf("inject %v", structPtrVal.Type())
+	p.Synthetic = true
+	for i, info := range fieldTags {
+		p.Inputs[i] = info.input(p.Inputs[i].Type)
+	}
+	b.appendNode(p)
 	return nil
 }
 
@@ -264,76 +600,226 @@ func checkInjectInTypes(count int, getType func(int) reflect.Type, kindStr strin
 		if checkType.Kind() == reflect.Struct && hasInject(checkType) {
 			return fmt.Errorf("provider %s type %v embeds bootstrap.Inject, which is prohibited", kindStr, t)
 		}
+		if isParamObject(checkType) {
+			return fmt.Errorf("provider %s type %v embeds bootstrap.In, which is only allowed for parameters passed by value", kindStr, t)
+		}
+		if isResultObject(checkType) {
+			return fmt.Errorf("provider %s type %v embeds bootstrap.Out, which is only allowed for results returned by value", kindStr, t)
+		}
 	}
 	return nil
 }
 func (b *Bootstrap) execute(p *dag.Node) error {
-	var args []reflect.Value
-	args = make([]reflect.Value, len(p.Inputs))
+	args, err := b.arguments(p)
+	if err != nil {
+		return err
+	}
+
+	results, err := call(p, args)
+	if err != nil {
+		return err
+	}
+
+	b.store(p, results)
+	return nil
+}
+
+// arguments collects the input values of p from the values built so far.
+func (b *Bootstrap) arguments(p *dag.Node) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(p.Inputs))
 
 	for i, in := range p.Inputs {
-		if val, ok := b.values[in]; ok {
+		if in.Group != "" {
+			args[i] = b.groupValue(in.Key)
+			continue
+		}
+		if val, ok := b.value(p, in.Key); ok {
 			args[i] = val
+		} else if in.Optional {
+			args[i] = reflect.Zero(in.Type)
 		} else {
-			return fmt.Errorf("internal error: missing value for type %v", in)
+			return nil, fmt.Errorf("internal error: missing value for %v", in)
 		}
 	}
+	return args, nil
+}
 
-	results := p.Fn.Call(args)
+// value returns the value of key visible to p, preferring one private to the module of p.
+func (b *Bootstrap) value(p *dag.Node, key dag.Key) (reflect.Value, bool) {
+	if len(p.Module) > 0 {
+		scoped := key
+		scoped.Module = p.Module[0]
+		if val, ok := b.values[scoped]; ok {
+			return val, true
+		}
+	}
+	val, ok := b.values[key]
+	return val, ok
+}
+
+// call invokes the constructor of p and checks its error returns. A panic is
+// converted into an error so that the run is rolled back like any other failure.
+// It does not touch the container, so it is safe to run concurrently.
+func call(p *dag.Node, args []reflect.Value) (results []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			results, err = nil, &ConstructorError{
+				Provider: p.String(),
+				Err:      fmt.Errorf("panic: %v", r),
+				Stack:    debug.Stack(),
+				node:     p,
+			}
+		}
+	}()
+
+	results = p.Fn.Call(args)
 
 	// Check error returns
 	for _, idx := range p.ErrorIndices {
 		errVal := results[idx]
 		if !errVal.IsNil() {
-			return errVal.Interface().(error)
+			return nil, &ConstructorError{Provider: p.String(), Err: errVal.Interface().(error), node: p}
 		}
 	}
+	return results, nil
+}
 
-	// Store results (excluding errors) and register cleanups
-	// Note: p.outputs corresponds to results excluding errors, BUT
-	// results contains EVERYTHING including errors at specific indices.
-	// We need to map outputs to results carefully.
+// store records the results of p in the container and registers their cleanups.
+func (b *Bootstrap) store(p *dag.Node, results []reflect.Value) {
+	// Store results (excluding errors) and register cleanups.
+	// Several outputs may share a return value when it is bound to
+	// additional interface types, so cleanups are tracked per result index.
+	registered := make(map[int]bool)
+	for _, out := range p.Outputs {
+		res := results[out.Index]
+
+		// A decorator returning the value it received must not clean it up twice,
+		// and values inherited from the parent are cleaned up by the parent.
+		if p.Decorator && same(res, b.values[out.Key]) || b.inherited[p] {
+			registered[out.Index] = true
+		}
 
-	outputIdx := 0
-	for i, res := range results {
-		// Check if this index was an error index
-		isError := false
-		for _, errIdx := range p.ErrorIndices {
-			if i == errIdx {
-				isError = true
-				break
+		// Store in values map, or collect as a group member
+		if out.Group != "" {
+			members := b.groups[out.Key]
+			if members == nil {
+				members = make(map[*dag.Node][]reflect.Value)
+				b.groups[out.Key] = members
 			}
+			if b.inherited[p] {
+				// Members inherited from the parent come as a single slice.
+				for i := range res.Len() {
+					members[p] = append(members[p], res.Index(i))
+				}
+			} else {
+				members[p] = append(members[p], res)
+			}
+		} else {
+			b.values[out.Key] = res
 		}
-		if isError {
+
+		if registered[out.Index] {
 			continue
 		}
+		registered[out.Index] = true
 
-		if outputIdx < len(p.Outputs) {
-			outType := p.Outputs[outputIdx]
-			outputIdx++
-
-			// Store in values map
-			b.values[outType] = res
+		// Register Cleanup
+		if !isNil(res) {
+			b.registerCleanup(p, res)
+			b.registerHooks(p, res)
+		}
+	}
 
-			// Register Cleanup
-			if res.IsValid() {
-				// Check for nil only on nillable types to avoid panic
-				isNil := false
-				switch res.Kind() {
-				case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
-					if res.IsNil() {
-						isNil = true
-					}
-				}
+	// Results taken over by a replacement are discarded, but they were built
+	// all the same and must be cleaned up.
+	for _, i := range b.replaced[p] {
+		if res := results[i]; !registered[i] && !isNil(res) {
+			registered[i] = true
+			b.registerCleanup(p, res)
+		}
+	}
 
-				if !isNil {
-					if cleanable, ok := res.Interface().(Cleanable); ok {
-						b.cleanups = append(b.cleanups, cleanable.Cleanup)
-					}
-				}
-			}
+	// Register the cleanup function returned alongside the outputs
+	if p.CleanupIndex >= 0 {
+		if fn := results[p.CleanupIndex]; !fn.IsNil() {
+			b.cleanups = append(b.cleanups, cleanup{
+				name: fmt.Sprintf("cleanup function from %s", p),
+				fn:   returnedCleanup(fn.Interface()),
+			})
 		}
 	}
+}
 
-	return nil
+// same reports whether a and b hold the same comparable dynamic value.
+func same(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return false
+	}
+	x, y := a.Interface(), b.Interface()
+	if x == nil || reflect.TypeOf(x) != reflect.TypeOf(y) || !reflect.ValueOf(x).Comparable() {
+		return false
+	}
+	return x == y
+}
+
+// registerHooks appends a lifecycle hook for a Startable or Stoppable result.
+// isNil reports whether res holds no value, checking for nil only on nillable types.
+func isNil(res reflect.Value) bool {
+	if !res.IsValid() {
+		return true
+	}
+	switch res.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return res.IsNil()
+	}
+	return false
+}
+
+// registerCleanup registers the cleanup of res, a result of p, if it has one. A cleanup
+// function returned by p owns the teardown of its results instead.
+func (b *Bootstrap) registerCleanup(p *dag.Node, res reflect.Value) {
+	if p.CleanupIndex >= 0 {
+		return
+	}
+	v := res.Interface()
+	if fn, ok := cleanupFunc(v, b.autoCleanup(reflect.TypeOf(v))); ok {
+		b.cleanups = append(b.cleanups, cleanup{name: componentName(p, res), fn: fn})
+	}
+}
+
+func (b *Bootstrap) registerHooks(p *dag.Node, res reflect.Value) {
+	startable, isStartable := res.Interface().(Startable)
+	stoppable, isStoppable := res.Interface().(Stoppable)
+	if !isStartable && !isStoppable {
+		return
+	}
+
+	h := Hook{Name: componentName(p, res)}
+	if isStartable {
+		h.OnStart = startable.Start
+	}
+	if isStoppable {
+		h.OnStop = stoppable.Stop
+	}
+	b.lifecycle.Append(h)
+}
+
+// componentName describes the result res of p in hook and cleanup errors.
+func componentName(p *dag.Node, res reflect.Value) string {
+	return fmt.Sprintf("%v from %s", res.Type(), p)
+}
+
+// groupValue assembles the slice for a group dependency, ordering members by registration.
+// Members inherited from the ancestors of a child container come first.
+func (b *Bootstrap) groupValue(in dag.Key) reflect.Value {
+	nodes := b.ran
+	if nodes == nil {
+		nodes = b.providers
+	}
+	members := b.groups[in.Elem()]
+	slice := reflect.MakeSlice(in.Type, 0, len(members))
+	for _, p := range nodes {
+		slice = reflect.Append(slice, members[p]...)
+	}
+	return slice
 }
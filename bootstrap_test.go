@@ -3,8 +3,13 @@ package bootstrap
 import (
 	"context"
 	"errors"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/viilon/bootstrap/dag"
 )
 
 // Helper types for testing
@@ -598,3 +603,2204 @@ func TestProhibitInjectEmbedInProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestNamedProviders(t *testing.T) {
+	type DB struct {
+		DSN string
+	}
+
+	type Repo struct {
+		Primary *DB
+		Replica *DB
+	}
+
+	t.Run("Constructor Parameters", func(t *testing.T) {
+		r := New()
+		var repo *Repo
+		r.Add(
+			Annotate(func() *DB { return &DB{DSN: "primary"} }, Name("primary")),
+			Annotate(func() *DB { return &DB{DSN: "replica"} }, Name("replica")),
+			Annotate(func(p, r *DB) *Repo {
+				return &Repo{Primary: p, Replica: r}
+			}, ParamTags(`name:"primary"`, `name:"replica"`)),
+			&repo,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if repo.Primary.DSN != "primary" || repo.Replica.DSN != "replica" {
+			t.Errorf("wrong injection: primary=%s replica=%s", repo.Primary.DSN, repo.Replica.DSN)
+		}
+	})
+
+	t.Run("Struct Fields And Targets", func(t *testing.T) {
+		type Target struct {
+			Inject
+			Primary *DB `name:"primary"`
+			Replica *DB `name:"replica"`
+		}
+
+		r := New()
+		var target Target
+		var replica *DB
+		r.Add(
+			Annotate(func() *DB { return &DB{DSN: "primary"} }, Name("primary")),
+			Annotate(func() *DB { return &DB{DSN: "replica"} }, Name("replica")),
+			&target,
+			Annotate(&replica, Name("replica")),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if target.Primary.DSN != "primary" || target.Replica.DSN != "replica" {
+			t.Errorf("wrong field injection: %+v", target)
+		}
+		if replica == nil || replica.DSN != "replica" {
+			t.Errorf("replica not populated: %v", replica)
+		}
+	})
+
+	t.Run("Unnamed And Named Coexist", func(t *testing.T) {
+		r := New()
+		var db *DB
+		r.Add(
+			func() *DB { return &DB{DSN: "default"} },
+			Annotate(func() *DB { return &DB{DSN: "replica"} }, Name("replica")),
+			&db,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if db.DSN != "default" {
+			t.Errorf("want default, got %s", db.DSN)
+		}
+	})
+
+	t.Run("Duplicate Name", func(t *testing.T) {
+		r := New()
+		r.Add(
+			Annotate(func() *DB { return &DB{} }, Name("primary")),
+			Annotate(func() *DB { return &DB{} }, Name("primary")),
+		)
+
+		err := r.Run()
+		if err == nil || !strings.Contains(err.Error(), "duplicate provider") {
+			t.Fatalf("expected duplicate provider error, got %v", err)
+		}
+	})
+
+	t.Run("Missing Name", func(t *testing.T) {
+		r := New()
+		r.Add(
+			func() *DB { return &DB{} },
+			Annotate(func(*DB) {}, ParamTags(`name:"replica"`)),
+		)
+
+		err := r.Run()
+		if err == nil || !strings.Contains(err.Error(), `name="replica"`) {
+			t.Fatalf("expected missing named dependency error, got %v", err)
+		}
+	})
+
+	t.Run("Too Many Param Tags", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func(*DB) {}, ParamTags(`name:"a"`, `name:"b"`)))
+
+		if err := r.Run(); err == nil {
+			t.Fatal("expected error for too many parameter tags, got nil")
+		}
+	})
+}
+
+type Migration interface {
+	Name() string
+}
+
+type namedMigration string
+
+func (m namedMigration) Name() string { return string(m) }
+
+func TestValueGroups(t *testing.T) {
+	newMigration := func(name string) func() Migration {
+		return func() Migration { return namedMigration(name) }
+	}
+
+	names := func(ms []Migration) []string {
+		out := make([]string, 0, len(ms))
+		for _, m := range ms {
+			out = append(out, m.Name())
+		}
+		return out
+	}
+
+	t.Run("Registration Order", func(t *testing.T) {
+		r := New()
+		var got []Migration
+		type Dep struct{}
+		r.Add(
+			Annotate(newMigration("first"), Group("migrations")),
+			// Depends on a provider registered later, so it executes later.
+			Annotate(func(*Dep) Migration { return namedMigration("second") }, Group("migrations")),
+			Annotate(newMigration("third"), Group("migrations")),
+			func() *Dep { return &Dep{} },
+			Annotate(func(ms []Migration) { got = ms }, ParamTags(`group:"migrations"`)),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if want := []string{"first", "second", "third"}; strings.Join(names(got), ",") != strings.Join(want, ",") {
+			t.Errorf("want %v, got %v", want, names(got))
+		}
+	})
+
+	t.Run("Fields And Targets", func(t *testing.T) {
+		type Runner struct {
+			Inject
+			Migrations []Migration `group:"migrations"`
+		}
+
+		r := New()
+		var runner Runner
+		var target []Migration
+		r.Add(
+			&runner,
+			Annotate(&target, Group("migrations")),
+			Annotate(newMigration("a"), Group("migrations")),
+			Annotate(newMigration("b"), Group("migrations")),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if len(runner.Migrations) != 2 || len(target) != 2 {
+			t.Errorf("want 2 members, got field=%v target=%v", names(runner.Migrations), names(target))
+		}
+	})
+
+	t.Run("Empty Group", func(t *testing.T) {
+		r := New()
+		var got []Migration
+		called := false
+		r.Add(Annotate(func(ms []Migration) {
+			called = true
+			got = ms
+		}, ParamTags(`group:"migrations"`)))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !called || got == nil || len(got) != 0 {
+			t.Errorf("want empty non-nil slice, got %v (called=%v)", got, called)
+		}
+	})
+
+	t.Run("Non Slice Consumer", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func(Migration) {}, ParamTags(`group:"migrations"`)))
+
+		err := r.Run()
+		if err == nil || !strings.Contains(err.Error(), "must be a slice") {
+			t.Fatalf("expected slice error, got %v", err)
+		}
+	})
+
+	t.Run("Name And Group Exclusive", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(newMigration("a"), Name("a"), Group("migrations")))
+
+		if err := r.Run(); err == nil {
+			t.Fatal("expected error for name and group, got nil")
+		}
+	})
+}
+
+func TestOptionalDependencies(t *testing.T) {
+	type Tracer struct {
+		Name string
+	}
+
+	t.Run("Missing Provider Injects Zero Value", func(t *testing.T) {
+		type Target struct {
+			Inject
+			Tracer *Tracer `optional:"true"`
+		}
+
+		r := New()
+		called := false
+		var field Target
+		var target *Tracer
+		r.Add(
+			Annotate(func(tr *Tracer) {
+				called = true
+				if tr != nil {
+					t.Errorf("want nil tracer, got %v", tr)
+				}
+			}, ParamTags(`optional:"true"`)),
+			&field,
+			Annotate(&target, Optional()),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !called {
+			t.Error("consumer not executed")
+		}
+		if field.Tracer != nil || target != nil {
+			t.Errorf("want nil values, got field=%v target=%v", field.Tracer, target)
+		}
+	})
+
+	t.Run("Existing Provider Is Ordered First", func(t *testing.T) {
+		r := New()
+		var got *Tracer
+		r.Add(
+			// Consumer registered before the provider
+			Annotate(func(tr *Tracer) { got = tr }, ParamTags(`optional:"true"`)),
+			func() *Tracer { return &Tracer{Name: "jaeger"} },
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if got == nil || got.Name != "jaeger" {
+			t.Errorf("want jaeger tracer, got %v", got)
+		}
+	})
+
+	t.Run("Invalid Tag", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func(*Tracer) {}, ParamTags(`optional:"maybe"`)))
+
+		if err := r.Run(); err == nil {
+			t.Fatal("expected error for invalid optional tag, got nil")
+		}
+	})
+}
+
+type Store interface {
+	Get(key string) string
+}
+
+type memoryStore struct {
+	cleanups int
+}
+
+func (s *memoryStore) Get(key string) string { return key }
+
+func (s *memoryStore) Cleanup() error {
+	s.cleanups++
+	return nil
+}
+
+func TestInterfaceBinding(t *testing.T) {
+	t.Run("Shared Instance", func(t *testing.T) {
+		r := New()
+		var concrete *memoryStore
+		var iface Store
+		r.Add(
+			Annotate(func() *memoryStore { return &memoryStore{} }, As[Store]()),
+			&concrete,
+			&iface,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if concrete == nil || iface != Store(concrete) {
+			t.Fatalf("want the same instance, got %p and %v", concrete, iface)
+		}
+
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if concrete.cleanups != 1 {
+			t.Errorf("want 1 cleanup, got %d", concrete.cleanups)
+		}
+	})
+
+	t.Run("Named Binding", func(t *testing.T) {
+		r := New()
+		var iface Store
+		r.Add(
+			Annotate(func() *memoryStore { return &memoryStore{} }, As[Store](), Name("cache")),
+			Annotate(&iface, Name("cache")),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if iface == nil {
+			t.Error("named interface not populated")
+		}
+	})
+
+	t.Run("Not Implemented", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func() *Config { return &Config{} }, As[Store]()))
+
+		err := r.Run()
+		if err == nil || !strings.Contains(err.Error(), "implements") {
+			t.Fatalf("expected binding error, got %v", err)
+		}
+	})
+
+	t.Run("Not An Interface", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func() *memoryStore { return &memoryStore{} }, As[*Config]()))
+
+		err := r.Run()
+		if err == nil || !strings.Contains(err.Error(), "not an interface") {
+			t.Fatalf("expected interface error, got %v", err)
+		}
+	})
+}
+
+func TestTypedErrors(t *testing.T) {
+	t.Run("Missing Dependency", func(t *testing.T) {
+		r := New()
+		r.Add(func(*Config) {})
+
+		err := r.Run()
+		var missing *MissingDependencyError
+		if !errors.As(err, &missing) {
+			t.Fatalf("want MissingDependencyError, got %v", err)
+		}
+		if missing.Key.Type != reflect.TypeOf(&Config{}) {
+			t.Errorf("want key *Config, got %v", missing.Key)
+		}
+		if !errors.Is(err, ErrMissingDependency) {
+			t.Error("errors.Is(err, ErrMissingDependency) = false")
+		}
+	})
+
+	t.Run("Duplicate Provider", func(t *testing.T) {
+		r := New()
+		r.Add(
+			func() *Config { return &Config{} },
+			func() *Config { return &Config{} },
+		)
+
+		err := r.Run()
+		var dup *DuplicateProviderError
+		if !errors.As(err, &dup) || dup.First == nil || dup.Second == nil {
+			t.Fatalf("want DuplicateProviderError, got %v", err)
+		}
+		if !errors.Is(err, ErrDuplicateProvider) {
+			t.Error("errors.Is(err, ErrDuplicateProvider) = false")
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		type A struct{}
+		type B struct{}
+		r := New()
+		r.Add(
+			func(*B) *A { return &A{} },
+			func(*A) *B { return &B{} },
+		)
+
+		err := r.Run()
+		var cycle *CycleError
+		if !errors.As(err, &cycle) {
+			t.Fatalf("want CycleError, got %v", err)
+		}
+		if len(cycle.Path) != 3 || cycle.Path[0] != cycle.Path[2] {
+			t.Errorf("want closed path of 3 nodes, got %v", cycle.Path)
+		}
+		if !errors.Is(err, ErrCycle) {
+			t.Error("errors.Is(err, ErrCycle) = false")
+		}
+	})
+
+	t.Run("Constructor Failure", func(t *testing.T) {
+		r := New()
+		r.Add(newBrokenConfig)
+
+		err := r.Run()
+		var ce *ConstructorError
+		if !errors.As(err, &ce) {
+			t.Fatalf("want ConstructorError, got %v", err)
+		}
+		if !strings.Contains(ce.Provider, "newBrokenConfig") {
+			t.Errorf("want provider label, got %q", ce.Provider)
+		}
+		if !errors.Is(err, errBrokenConfig) {
+			t.Error("constructor error does not wrap the original error")
+		}
+	})
+
+	t.Run("Registration", func(t *testing.T) {
+		r := New()
+		r.Add(42)
+
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) || re.Arg != 42 {
+			t.Fatalf("want RegistrationError for 42, got %v", err)
+		}
+	})
+}
+
+var errBrokenConfig = errors.New("dial failed")
+
+func newBrokenConfig() (*Config, error) {
+	return nil, errBrokenConfig
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Complete Wiring", func(t *testing.T) {
+		r := New()
+		called := false
+		var cfg *Config
+		r.Add(
+			func() *Config {
+				called = true
+				return &Config{}
+			},
+			&cfg,
+		)
+
+		if err := r.Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		if called || cfg != nil {
+			t.Error("Validate must not invoke providers or populate targets")
+		}
+	})
+
+	t.Run("All Problems", func(t *testing.T) {
+		type Missing struct{}
+		r := New()
+		r.Add(
+			func() *Config { return &Config{} },
+			func() *Config { return &Config{} },
+			func(*Missing) *Service { return &Service{} },
+			func(*Missing, *App) {},
+		)
+
+		err := r.Validate()
+		if !errors.Is(err, ErrDuplicateProvider) || !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("want duplicate and missing errors, got %v", err)
+		}
+		if n := strings.Count(err.Error(), "missing dependency"); n != 3 {
+			t.Errorf("want 3 missing dependencies, got %d: %v", n, err)
+		}
+	})
+
+	t.Run("Registration Error", func(t *testing.T) {
+		r := New()
+		r.Add("invalid")
+
+		var re *RegistrationError
+		if err := r.Validate(); !errors.As(err, &re) {
+			t.Fatalf("want RegistrationError, got %v", err)
+		}
+	})
+}
+
+func TestRollback(t *testing.T) {
+	t.Run("Cleans Up Built Components", func(t *testing.T) {
+		r := New()
+		var order []int
+		cleaner1Order = &order
+		cleaner2Order = &order
+		defer func() {
+			cleaner1Order = nil
+			cleaner2Order = nil
+		}()
+
+		r.Add(
+			func() *Cleaner1 { return &Cleaner1{} },
+			func(*Cleaner1) *Cleaner2 { return &Cleaner2{} },
+			func(*Cleaner2) (*Config, error) { return nil, errBrokenConfig },
+		)
+
+		err := r.Run()
+		if !errors.Is(err, errBrokenConfig) {
+			t.Fatalf("want construction error, got %v", err)
+		}
+		if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+			t.Errorf("rollback order wrong, want [2 1], got %v", order)
+		}
+
+		// Rolled back components are not cleaned up again.
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if len(order) != 2 {
+			t.Errorf("components cleaned up twice: %v", order)
+		}
+	})
+
+	t.Run("Combines Cleanup Errors", func(t *testing.T) {
+		r := New()
+		cleanupErr := errors.New("cleanup failed")
+		r.Add(
+			func() *FailingCleaner { return &FailingCleaner{Err: cleanupErr} },
+			func(*FailingCleaner) (*Config, error) { return nil, errBrokenConfig },
+		)
+
+		err := r.Run()
+		if !errors.Is(err, errBrokenConfig) || !errors.Is(err, cleanupErr) {
+			t.Fatalf("want both construction and cleanup errors, got %v", err)
+		}
+	})
+
+	t.Run("Discards Built Values", func(t *testing.T) {
+		for _, workers := range []int{0, 2} {
+			fail := true
+			var ms []Migration
+			r := New().WithConcurrency(workers)
+			r.Add(
+				func() *memoryStore { return &memoryStore{} },
+				Annotate(func() Migration { return namedMigration("m1") }, Group("migrations")),
+				Annotate(func(*memoryStore, []Migration) (*Config, error) {
+					if fail {
+						return nil, errBrokenConfig
+					}
+					return &Config{}, nil
+				}, ParamTags(``, `group:"migrations"`)),
+				Annotate(&ms, Group("migrations")),
+			)
+
+			if err := r.Run(); !errors.Is(err, errBrokenConfig) {
+				t.Fatalf("workers=%d: want construction error, got %v", workers, err)
+			}
+			if _, err := Get[*memoryStore](r); !errors.Is(err, ErrValueNotFound) {
+				t.Errorf("workers=%d: want rolled back value discarded, got %v", workers, err)
+			}
+			if values := r.Values(); len(values) != 0 {
+				t.Errorf("workers=%d: rolled back values listed: %v", workers, values)
+			}
+
+			fail = false
+			if err := r.Run(); err != nil {
+				t.Fatalf("workers=%d: retry failed: %v", workers, err)
+			}
+			if len(ms) != 1 {
+				t.Errorf("workers=%d: want one group member after retry, got %v", workers, ms)
+			}
+		}
+	})
+}
+
+func TestConcurrentExecution(t *testing.T) {
+	type Kafka struct{}
+	type Redis struct{}
+	type Postgres struct{}
+
+	t.Run("Independent Constructors Overlap", func(t *testing.T) {
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		track := func() func() {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			return func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}
+		}
+
+		r := New().WithConcurrency(2)
+		var app *App
+		r.Add(
+			func() *Kafka { defer track()(); return &Kafka{} },
+			func() *Redis { defer track()(); return &Redis{} },
+			func() *Postgres { defer track()(); return &Postgres{} },
+			func(*Kafka, *Redis, *Postgres) *App { return &App{} },
+			&app,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if app == nil {
+			t.Fatal("dependent not executed")
+		}
+		if maxRunning != 2 {
+			t.Errorf("want 2 constructors running at once, got %d", maxRunning)
+		}
+	})
+
+	t.Run("Dependency Order And Cleanup", func(t *testing.T) {
+		r := New().WithConcurrency(4)
+		var order []int
+		cleaner1Order = &order
+		cleaner2Order = &order
+		defer func() {
+			cleaner1Order = nil
+			cleaner2Order = nil
+		}()
+
+		r.Add(
+			func(*Cleaner1) *Cleaner2 { return &Cleaner2{} },
+			func() *Cleaner1 {
+				time.Sleep(10 * time.Millisecond)
+				return &Cleaner1{}
+			},
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+			t.Errorf("cleanup order wrong, want [2 1], got %v", order)
+		}
+	})
+
+	t.Run("First Error Cancels Context", func(t *testing.T) {
+		r := New().WithConcurrency(2)
+		started := make(chan struct{})
+		var canceled bool
+		r.Add(
+			func(ctx context.Context) *Kafka {
+				close(started)
+				select {
+				case <-ctx.Done():
+					canceled = true
+				case <-time.After(time.Second):
+				}
+				return &Kafka{}
+			},
+			func() (*Redis, error) {
+				<-started
+				return nil, errBrokenConfig
+			},
+			func(*Redis) *Postgres {
+				t.Error("dependent of failed constructor executed")
+				return nil
+			},
+		)
+
+		err := r.Run()
+		if !errors.Is(err, errBrokenConfig) {
+			t.Fatalf("want construction error, got %v", err)
+		}
+		if !canceled {
+			t.Error("context not canceled after first error")
+		}
+	})
+
+	t.Run("Retry After Failure", func(t *testing.T) {
+		fail := true
+		var failed, retried context.Context
+		r := New().WithConcurrency(2)
+		child := r.Child()
+		r.Add(
+			func(ctx context.Context) (*Kafka, error) {
+				if fail {
+					failed = ctx
+					return nil, errBrokenConfig
+				}
+				retried = ctx
+				return &Kafka{}, ctx.Err()
+			},
+		)
+
+		if err := r.Run(); !errors.Is(err, errBrokenConfig) {
+			t.Fatalf("want construction error, got %v", err)
+		}
+		if failed.Err() == nil {
+			t.Error("context of the failed run not canceled")
+		}
+
+		fail = false
+		if err := r.Run(); err != nil {
+			t.Fatalf("retry failed: %v", err)
+		}
+		if retried.Err() != nil {
+			t.Errorf("retry got a canceled context: %v", retried.Err())
+		}
+		// A failure of the parent does not cancel its children.
+		var ctx context.Context
+		if err := child.Add(&ctx).Run(); err != nil || ctx.Err() != nil {
+			t.Errorf("child context canceled by the parent's failure: %v, %v", err, ctx.Err())
+		}
+	})
+
+	t.Run("Panic Becomes Error", func(t *testing.T) {
+		for _, workers := range []int{0, 2} {
+			cleaned := false
+			r := New().WithConcurrency(workers)
+			r.Add(
+				func() (*Redis, func()) { return &Redis{}, func() { cleaned = true } },
+				func(*Redis) *Kafka { panic("boom") },
+			)
+
+			var ce *ConstructorError
+			if err := r.Run(); !errors.As(err, &ce) || !strings.Contains(err.Error(), "boom") {
+				t.Fatalf("workers=%d: want ConstructorError with panic, got %v", workers, err)
+			}
+			// The stack locates the panic inside the constructor.
+			if !strings.Contains(string(ce.Stack), "bootstrap_test.go") || !strings.Contains(ce.Error(), "goroutine") {
+				t.Errorf("workers=%d: want the stack trace of the panic, got %q", workers, ce.Stack)
+			}
+			if !cleaned {
+				t.Errorf("workers=%d: built components not rolled back", workers)
+			}
+		}
+	})
+}
+
+type lifecycleServer struct {
+	name   string
+	events *[]string
+}
+
+func (s *lifecycleServer) Start(ctx context.Context) error {
+	*s.events = append(*s.events, "start "+s.name)
+	return nil
+}
+
+func (s *lifecycleServer) Stop(ctx context.Context) error {
+	*s.events = append(*s.events, "stop "+s.name)
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	type Listener struct{}
+
+	t.Run("Start And Stop Order", func(t *testing.T) {
+		var events []string
+		r := New()
+		r.Add(
+			// Registered first but depends on the Startable below.
+			func(lc Lifecycle, _ *lifecycleServer) *Listener {
+				lc.Append(Hook{
+					OnStart: func(context.Context) error {
+						events = append(events, "start hook")
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						events = append(events, "stop hook")
+						return nil
+					},
+				})
+				return &Listener{}
+			},
+			func() *lifecycleServer {
+				events = append(events, "construct")
+				return &lifecycleServer{name: "db", events: &events}
+			},
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("components started during Run: %v", events)
+		}
+		if err := r.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		if err := r.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+
+		want := "construct,start db,start hook,stop hook,stop db"
+		if got := strings.Join(events, ","); got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	})
+
+	t.Run("Failed Start Stops Started Hooks", func(t *testing.T) {
+		var events []string
+		r := New()
+		startErr := errors.New("bind failed")
+		r.Add(
+			func() *lifecycleServer { return &lifecycleServer{name: "db", events: &events} },
+			func(lc Lifecycle, _ *lifecycleServer) *Config {
+				lc.Append(Hook{
+					Name:    "http",
+					OnStart: func(context.Context) error { return startErr },
+				})
+				return &Config{}
+			},
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		err := r.Start(context.Background())
+		if !errors.Is(err, startErr) || !strings.Contains(err.Error(), "http") {
+			t.Fatalf("want start error naming hook, got %v", err)
+		}
+		if got := strings.Join(events, ","); got != "start db,stop db" {
+			t.Errorf("want start db,stop db, got %s", got)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r := New().WithTimeouts(10*time.Millisecond, 10*time.Millisecond)
+		r.Add(func(lc Lifecycle) *Config {
+			lc.Append(Hook{OnStart: func(context.Context) error {
+				time.Sleep(time.Second) // ignores the context
+				return nil
+			}})
+			return &Config{}
+		})
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("Cleanup Stops Started Components", func(t *testing.T) {
+		var events []string
+		r := New()
+		r.Add(func() *lifecycleServer { return &lifecycleServer{name: "db", events: &events} })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if got := strings.Join(events, ","); got != "start db,stop db" {
+			t.Errorf("want start db,stop db, got %s", got)
+		}
+	})
+}
+
+type hungPool struct {
+	release chan struct{}
+}
+
+func (p *hungPool) Cleanup() error {
+	<-p.release
+	return nil
+}
+
+type contextPool struct {
+	gotDeadline bool
+}
+
+func (p *contextPool) CleanupContext(ctx context.Context) error {
+	_, p.gotDeadline = ctx.Deadline()
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("Hung Cleanup Times Out", func(t *testing.T) {
+		pool := &hungPool{release: make(chan struct{})}
+		defer close(pool.release)
+
+		r := New().WithTimeouts(time.Second, 20*time.Millisecond)
+		svc := &Service{}
+		r.Add(
+			func() *Service { return svc },
+			func(*Service) *hungPool { return pool },
+		)
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err := r.Shutdown(ctx)
+		var ce *CleanupError
+		if !errors.As(err, &ce) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want CleanupError with deadline exceeded, got %v", err)
+		}
+		if !strings.Contains(ce.Component, "hungPool") {
+			t.Errorf("want component naming hungPool, got %q", ce.Component)
+		}
+		if !svc.CleanedUp {
+			t.Error("remaining cleanups not executed after timeout")
+		}
+	})
+
+	t.Run("Context Cleanable", func(t *testing.T) {
+		r := New()
+		pool := &contextPool{}
+		r.Add(func() *contextPool { return pool })
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := r.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown failed: %v", err)
+		}
+		if !pool.gotDeadline {
+			t.Error("CleanupContext did not receive a deadline-bound context")
+		}
+	})
+}
+
+type (
+	closerConn     struct{ closed bool }
+	countingCloser struct{ closes int }
+	plainCloser    struct{ closed bool }
+	stopperWorker  struct{ stopped bool }
+	shutdownServer struct{ shutdown bool }
+)
+
+func (c *closerConn) Close() error { c.closed = true; return nil }
+
+func (c *countingCloser) Close() error {
+	c.closes++
+	if c.closes > 1 {
+		return errors.New("already closed")
+	}
+	return nil
+}
+
+func (c *plainCloser) Close() { c.closed = true }
+
+func (w *stopperWorker) Stop() { w.stopped = true }
+
+func (s *shutdownServer) Shutdown(context.Context) error { s.shutdown = true; return nil }
+
+func TestAutoCleanup(t *testing.T) {
+	t.Run("Conventional Methods", func(t *testing.T) {
+		conn, closer, worker, server := &closerConn{}, &plainCloser{}, &stopperWorker{}, &shutdownServer{}
+		r := New()
+		r.Add(
+			func() *closerConn { return conn },
+			func() *plainCloser { return closer },
+			func() *stopperWorker { return worker },
+			func() *shutdownServer { return server },
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if !conn.closed || !closer.closed || !worker.stopped || !server.shutdown {
+			t.Errorf("not all components cleaned up: %v %v %v %v", conn.closed, closer.closed, worker.stopped, server.shutdown)
+		}
+	})
+
+	t.Run("Disabled For Type", func(t *testing.T) {
+		conn, worker := &closerConn{}, &stopperWorker{}
+		r := New().WithoutAutoCleanup(reflect.TypeOf(conn))
+		r.Add(
+			func() *closerConn { return conn },
+			func() *stopperWorker { return worker },
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if conn.closed || !worker.stopped {
+			t.Errorf("want only worker cleaned up, got closed=%v stopped=%v", conn.closed, worker.stopped)
+		}
+	})
+
+	t.Run("Disabled Entirely", func(t *testing.T) {
+		conn, svc := &closerConn{}, &Service{}
+		r := New().WithoutAutoCleanup()
+		r.Add(
+			func() *closerConn { return conn },
+			func() *Service { return svc },
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if conn.closed || !svc.CleanedUp {
+			t.Errorf("want only Cleanable cleaned up, got closed=%v cleaned=%v", conn.closed, svc.CleanedUp)
+		}
+	})
+}
+
+func TestReturnedCleanup(t *testing.T) {
+	type Pool struct{}
+
+	t.Run("Cleanup Order", func(t *testing.T) {
+		var events []string
+		r := New()
+		r.Add(
+			func(*Config) (*Pool, func(), error) {
+				return &Pool{}, func() { events = append(events, "pool") }, nil
+			},
+			func() (*Config, func() error) {
+				return &Config{}, func() error {
+					events = append(events, "config")
+					return nil
+				}
+			},
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if got := strings.Join(events, ","); got != "pool,config" {
+			t.Errorf("want pool,config, got %s", got)
+		}
+	})
+
+	t.Run("Error Propagation", func(t *testing.T) {
+		cleanupErr := errors.New("close failed")
+		r := New()
+		r.Add(func() (*Pool, func() error) {
+			return &Pool{}, func() error { return cleanupErr }
+		})
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); !errors.Is(err, cleanupErr) {
+			t.Fatalf("want cleanup error, got %v", err)
+		}
+	})
+
+	t.Run("Replaces Detected Cleanup", func(t *testing.T) {
+		r := New()
+		r.Add(func() (*countingCloser, func() error) {
+			c := &countingCloser{}
+			return c, c.Close
+		})
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if c, _ := Get[*countingCloser](r); c.closes != 1 {
+			t.Errorf("want Close called once, got %d", c.closes)
+		}
+	})
+
+	t.Run("Not An Output", func(t *testing.T) {
+		r := New()
+		r.Add(
+			func() (*Pool, func()) { return &Pool{}, func() {} },
+			func(func()) {},
+		)
+
+		if err := r.Run(); !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("want missing func() dependency, got %v", err)
+		}
+	})
+
+	t.Run("Sole Function Output Is A Value", func(t *testing.T) {
+		r := New()
+		called := false
+		r.Add(
+			func() func() { return func() { called = true } },
+			func(fn func()) { fn() },
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !called {
+			t.Error("func() value not injected")
+		}
+	})
+}
+
+func TestParameterObjects(t *testing.T) {
+	type Tracer struct{}
+	type Server struct {
+		Cfg        *Config
+		Primary    Store
+		Tracer     *Tracer
+		Migrations []Migration
+		Port       int
+	}
+	type ServerParams struct {
+		In
+
+		Config     *Config
+		Primary    Store       `name:"primary"`
+		Tracer     *Tracer     `optional:"true"`
+		Migrations []Migration `group:"migrations"`
+		internal   *Config     // unexported fields are ignored
+	}
+
+	t.Run("Fields Resolved As Dependencies", func(t *testing.T) {
+		r := New()
+		var srv *Server
+		r.Add(
+			func() *Config { return &Config{Val: "cfg"} },
+			Annotate(func() *memoryStore { return &memoryStore{} }, As[Store](), Name("primary")),
+			Annotate(func() Migration { return namedMigration("m1") }, Group("migrations")),
+			Annotate(func(p ServerParams, port int) *Server {
+				if p.internal != nil {
+					t.Error("unexported field injected")
+				}
+				return &Server{Cfg: p.Config, Primary: p.Primary, Tracer: p.Tracer, Migrations: p.Migrations, Port: port}
+			}, ParamTags(``, `name:"port"`)),
+			Annotate(func() int { return 8080 }, Name("port")),
+			&srv,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if srv.Cfg.Val != "cfg" || srv.Primary == nil || srv.Tracer != nil || len(srv.Migrations) != 1 || srv.Port != 8080 {
+			t.Errorf("unexpected server: %+v", srv)
+		}
+	})
+
+	t.Run("Missing Field Dependency Names Constructor", func(t *testing.T) {
+		r := New()
+		r.Add(newServerFromParams)
+
+		err := r.Run()
+		var missing *MissingDependencyError
+		if !errors.As(err, &missing) || missing.Key.Type != reflect.TypeOf(&Config{}) {
+			t.Fatalf("want missing *Config, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "newServerFromParams") {
+			t.Errorf("want error naming the constructor, got %v", err)
+		}
+	})
+
+	t.Run("Pointer Parameter Object", func(t *testing.T) {
+		r := New()
+		r.Add(func(*ServerParams) {})
+
+		if err := r.Run(); err == nil || !strings.Contains(err.Error(), "bootstrap.In") {
+			t.Fatalf("expected parameter object error, got %v", err)
+		}
+	})
+}
+
+type serverParams struct {
+	In
+
+	Config *Config
+}
+
+func newServerFromParams(p serverParams) *Service {
+	return &Service{Cfg: p.Config}
+}
+
+type HealthCheck func() error
+
+func TestResultObjects(t *testing.T) {
+	type Client struct{}
+	type Metrics struct{}
+	type ModuleOut struct {
+		Out
+
+		Client  *Client
+		Metrics *Metrics    `name:"client"`
+		Health  HealthCheck `group:"health"`
+		Cleaner *Cleaner1
+		hidden  *Config // unexported fields are ignored
+	}
+
+	t.Run("Fields Registered As Outputs", func(t *testing.T) {
+		var order []int
+		cleaner1Order = &order
+		defer func() { cleaner1Order = nil }()
+
+		r := New()
+		var client *Client
+		var metrics *Metrics
+		var checks []HealthCheck
+		closed := false
+		r.Add(
+			func() (ModuleOut, func(), error) {
+				return ModuleOut{
+					Client:  &Client{},
+					Metrics: &Metrics{},
+					Health:  func() error { return nil },
+					Cleaner: &Cleaner1{},
+				}, func() { closed = true }, nil
+			},
+			&client,
+			Annotate(&metrics, Name("client")),
+			Annotate(&checks, Group("health")),
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if client == nil || metrics == nil || len(checks) != 1 {
+			t.Errorf("fields not provided: client=%v metrics=%v checks=%d", client, metrics, len(checks))
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		// The returned cleanup function owns the teardown of every field.
+		if !closed || len(order) != 0 {
+			t.Errorf("want only the returned cleanup run: closed=%v cleaner=%v", closed, order)
+		}
+	})
+
+	t.Run("Function Field Is A Value", func(t *testing.T) {
+		type Out2 struct {
+			Out
+
+			Client *Client
+			Stop   func()
+		}
+
+		r := New()
+		var stop func()
+		r.Add(
+			func() Out2 { return Out2{Client: &Client{}, Stop: func() {}} },
+			&stop,
+		)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if stop == nil {
+			t.Error("function field not provided")
+		}
+	})
+
+	t.Run("Name Option Rejected", func(t *testing.T) {
+		r := New()
+		r.Add(Annotate(func() ModuleOut { return ModuleOut{} }, Name("x")))
+
+		if err := r.Run(); err == nil {
+			t.Fatal("expected error for name option on result object, got nil")
+		}
+	})
+
+	t.Run("Pointer Result Object", func(t *testing.T) {
+		r := New()
+		r.Add(func() *ModuleOut { return &ModuleOut{} })
+
+		if err := r.Run(); err == nil || !strings.Contains(err.Error(), "bootstrap.Out") {
+			t.Fatalf("expected result object error, got %v", err)
+		}
+	})
+}
+
+func TestGenericAPI(t *testing.T) {
+	t.Run("Provide Supply Get", func(t *testing.T) {
+		r := New()
+		store := &memoryStore{}
+		Supply[*Config](r, &Config{Val: "supplied"})
+		Supply[Store](r, store, Name("cache"))
+		Supply(r, 8080, Name("port"))
+		Provide1(r, func(c *Config) (*Service, error) { return &Service{Cfg: c}, nil })
+		Provide[Migration](r, func() (Migration, error) { return namedMigration("m1"), nil }, Group("migrations"))
+		Provide2(r, func(c *Config, s *Service) (*CacheClient, error) { return &CacheClient{Redis: &Redis{}}, nil })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		svc, err := Get[*Service](r)
+		if err != nil || svc.Cfg.Val != "supplied" {
+			t.Errorf("Get[*Service] = %v, %v", svc, err)
+		}
+		cache, err := Get[Store](r, Name("cache"))
+		if err != nil || cache != store {
+			t.Errorf("Get[Store] = %v, %v", cache, err)
+		}
+		port, err := Get[int](r, Name("port"))
+		if err != nil || port != 8080 {
+			t.Errorf("Get[int] = %v, %v", port, err)
+		}
+		ms, err := Get[[]Migration](r, Group("migrations"))
+		if err != nil || len(ms) != 1 {
+			t.Errorf("Get[[]Migration] = %v, %v", ms, err)
+		}
+		if client, err := Get[*CacheClient](r); err != nil || client.Redis == nil {
+			t.Errorf("Get[*CacheClient] = %v, %v", client, err)
+		}
+	})
+
+	t.Run("Provide Arities", func(t *testing.T) {
+		type Tracer struct{}
+		type Server struct{ Deps int }
+		r := New()
+		ProvideNoErr(r, func() *Config { return &Config{} })
+		ProvideNoErr1(r, func(*Config) *Redis { return &Redis{} })
+		ProvideNoErr2(r, func(*Config, *Redis) *Tracer { return &Tracer{} })
+		ProvideNoErr3(r, func(*Config, *Redis, *Tracer) *Server { return &Server{Deps: 3} }, Name("noerr"))
+		Provide3(r, func(*Config, *Redis, *Tracer) (*Server, error) { return &Server{Deps: 3}, nil })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		for _, opts := range [][]Option{nil, {Name("noerr")}} {
+			if srv, err := Get[*Server](r, opts...); err != nil || srv.Deps != 3 {
+				t.Errorf("Get[*Server](%d options) = %v, %v", len(opts), srv, err)
+			}
+		}
+	})
+
+	t.Run("Get Before Run", func(t *testing.T) {
+		r := New()
+		Supply(r, &Config{})
+
+		if _, err := Get[*Config](r); !errors.Is(err, ErrValueNotFound) {
+			t.Fatalf("want ErrValueNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Provide Is Typed", func(t *testing.T) {
+		// Provide[*Service](r, func() (*Config, error) { ... }) does not compile.
+		var provide func(*Bootstrap, func() (*Service, error), ...Option) *Bootstrap = Provide[*Service]
+
+		constructor := reflect.TypeOf(provide).In(1)
+		if reflect.TypeOf(func() (*Config, error) { return nil, nil }).AssignableTo(constructor) {
+			t.Errorf("%v accepts a constructor of another type", constructor)
+		}
+		if reflect.TypeOf(func() *Service { return nil }).AssignableTo(constructor) {
+			t.Errorf("%v accepts a constructor without an error", constructor)
+		}
+
+		var provideNoErr func(*Bootstrap, func() *Service, ...Option) *Bootstrap = ProvideNoErr[*Service]
+		if constructor := reflect.TypeOf(provideNoErr).In(1); reflect.TypeOf(func() *Config { return nil }).AssignableTo(constructor) {
+			t.Errorf("%v accepts a constructor of another type", constructor)
+		}
+	})
+
+	t.Run("Supply Same Type Twice", func(t *testing.T) {
+		r := New()
+		Supply(r, &Config{Val: "a"}, Name("a"))
+		Supply(r, &Config{Val: "b"}, Name("b"))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if b, _ := Get[*Config](r, Name("b")); b == nil || b.Val != "b" {
+			t.Errorf("want b, got %v", b)
+		}
+	})
+}
+
+func TestSupply(t *testing.T) {
+	t.Run("Values And Bindings", func(t *testing.T) {
+		r := New()
+		store := &memoryStore{}
+		var got Store
+		var cfg *Config
+		r.Supply(&Config{Val: "ready"}, Annotate(store, As[Store](), Name("cache")))
+		r.Add(&cfg, Annotate(&got, Name("cache")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if cfg.Val != "ready" || got != store {
+			t.Errorf("got %v and %v", cfg, got)
+		}
+
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if store.cleanups != 1 {
+			t.Errorf("want supplied value cleaned up once, got %d", store.cleanups)
+		}
+	})
+
+	t.Run("Duplicate Of Constructor", func(t *testing.T) {
+		r := New()
+		r.Supply(&Config{})
+		r.Add(func() *Config { return &Config{} })
+
+		var dup *DuplicateProviderError
+		err := r.Run()
+		if !errors.As(err, &dup) {
+			t.Fatalf("want duplicate provider error, got %v", err)
+		}
+		if !strings.Contains(dup.First.String(), "supplied *bootstrap.Config") ||
+			!strings.Contains(dup.First.String(), "bootstrap_test.go") {
+			t.Errorf("want label naming the supplied value and call site, got %q", dup.First)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		r := New()
+		r.Supply(nil)
+
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) {
+			t.Fatalf("want registration error, got %v", err)
+		}
+	})
+
+	t.Run("Binding Not Implemented", func(t *testing.T) {
+		r := New()
+		r.Supply(Annotate(&Config{}, As[Store]()))
+
+		if err := r.Run(); err == nil || !strings.Contains(err.Error(), "cannot bind") {
+			t.Fatalf("want binding error, got %v", err)
+		}
+	})
+}
+
+type prefixLogger struct {
+	prefix string
+}
+
+func TestDecorators(t *testing.T) {
+	t.Run("Chain", func(t *testing.T) {
+		r := New()
+		var logger *prefixLogger
+		r.Add(&logger)
+		r.Decorate(func(l *prefixLogger) *prefixLogger {
+			return &prefixLogger{prefix: l.prefix + "+metrics"}
+		})
+		r.Decorate(func(l *prefixLogger, cfg *Config) (*prefixLogger, error) {
+			return &prefixLogger{prefix: l.prefix + "+" + cfg.Val}, nil
+		})
+		r.Add(func() *prefixLogger { return &prefixLogger{prefix: "base"} })
+		r.Supply(&Config{Val: "tracing"})
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if logger.prefix != "base+metrics+tracing" {
+			t.Errorf("want decorators applied in order, got %q", logger.prefix)
+		}
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		r := New()
+		var primary, replica *prefixLogger
+		r.Add(
+			Annotate(func() *prefixLogger { return &prefixLogger{prefix: "primary"} }, Name("primary")),
+			Annotate(func() *prefixLogger { return &prefixLogger{prefix: "replica"} }, Name("replica")),
+			Annotate(&primary, Name("primary")),
+			Annotate(&replica, Name("replica")),
+		)
+		r.Decorate(Annotate(func(l *prefixLogger) *prefixLogger {
+			return &prefixLogger{prefix: l.prefix + "+decorated"}
+		}, Name("replica")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if primary.prefix != "primary" || replica.prefix != "replica+decorated" {
+			t.Errorf("got %q and %q", primary.prefix, replica.prefix)
+		}
+	})
+
+	t.Run("Cleanup Once", func(t *testing.T) {
+		r := New()
+		store := &memoryStore{}
+		r.Supply(Annotate(store, As[Store]()))
+		r.Decorate(func(s Store) Store { return s })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if store.cleanups != 1 {
+			t.Errorf("want one cleanup, got %d", store.cleanups)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		r := New().WithConcurrency(4)
+		var logger *prefixLogger
+		r.Add(func() *prefixLogger { return &prefixLogger{prefix: "base"} }, &logger)
+		for _, suffix := range []string{"a", "b", "c"} {
+			r.Decorate(func(l *prefixLogger) *prefixLogger {
+				return &prefixLogger{prefix: l.prefix + suffix}
+			})
+		}
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if logger.prefix != "baseabc" {
+			t.Errorf("want decorators applied in order, got %q", logger.prefix)
+		}
+	})
+
+	t.Run("Nothing To Decorate", func(t *testing.T) {
+		r := New()
+		r.Decorate(func(l *prefixLogger) *prefixLogger { return l })
+
+		if err := r.Run(); !errors.Is(err, ErrMissingDependency) {
+			t.Fatalf("want missing dependency, got %v", err)
+		}
+	})
+
+	t.Run("Must Take Its Output", func(t *testing.T) {
+		r := New()
+		r.Decorate(func(cfg *Config) *prefixLogger { return nil })
+
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) {
+			t.Fatalf("want registration error, got %v", err)
+		}
+	})
+}
+
+func TestReplace(t *testing.T) {
+	t.Run("Before And After Registration", func(t *testing.T) {
+		for _, replaceFirst := range []bool{true, false} {
+			r := New()
+			fake := &memoryStore{}
+			called := false
+			var got Store
+			production := func() Store { called = true; return &memoryStore{} }
+
+			if replaceFirst {
+				r.Replace(func() Store { return fake })
+			}
+			r.Add(production, &got)
+			if !replaceFirst {
+				r.Replace(func() Store { return fake })
+			}
+
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if got != fake || called {
+				t.Errorf("replaceFirst=%v: want fake without invoking production, got %v (called=%v)", replaceFirst, got, called)
+			}
+			overrides := r.Overrides()
+			if len(overrides) != 1 || len(overrides[0].Replaced) != 1 || !strings.Contains(overrides[0].Replaced[0], "bootstrap_test.go") {
+				t.Errorf("replaceFirst=%v: unexpected overrides %+v", replaceFirst, overrides)
+			}
+		}
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		r := New()
+		var primary, replica *Config
+		r.Add(
+			Annotate(func() *Config { return &Config{Val: "primary"} }, Name("primary")),
+			Annotate(func() *Config { return &Config{Val: "replica"} }, Name("replica")),
+			Annotate(&primary, Name("primary")),
+			Annotate(&replica, Name("replica")),
+		)
+		r.Replace(Annotate(func() *Config { return &Config{Val: "fake"} }, Name("replica")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if primary.Val != "primary" || replica.Val != "fake" {
+			t.Errorf("got %q and %q", primary.Val, replica.Val)
+		}
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		r := New()
+		var ms []Migration
+		r.Add(
+			Annotate(func() Migration { return namedMigration("m1") }, Group("migrations")),
+			Annotate(func() Migration { return namedMigration("m2") }, Group("migrations")),
+			Annotate(&ms, Group("migrations")),
+		)
+		r.Replace(Annotate(func() Migration { return namedMigration("fake") }, Group("migrations")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if len(ms) != 1 || ms[0].Name() != "fake" {
+			t.Errorf("want only the replacement in the group, got %v", ms)
+		}
+		if o := r.Overrides(); len(o) != 1 || len(o[0].Replaced) != 2 {
+			t.Errorf("want both members recorded, got %+v", o)
+		}
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		r := New()
+		var cfg *Config
+		var logger *prefixLogger
+		r.Add(func() (*Config, *prefixLogger) {
+			return &Config{Val: "real"}, &prefixLogger{prefix: "real"}
+		}, &cfg, &logger)
+		r.Replace(func() *Config { return &Config{Val: "fake"} })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if cfg.Val != "fake" || logger.prefix != "real" {
+			t.Errorf("got %q and %q", cfg.Val, logger.prefix)
+		}
+	})
+
+	t.Run("Partial Cleans Up Discarded Result", func(t *testing.T) {
+		original, fake := &closerConn{}, &closerConn{}
+		var conn *closerConn
+		r := New().Add(func() (*closerConn, *prefixLogger) {
+			return original, &prefixLogger{}
+		}, &conn)
+		r.Replace(func() *closerConn { return fake })
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if conn != fake {
+			t.Fatalf("want fake injected, got %v", conn)
+		}
+		if err := r.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if !original.closed || !fake.closed {
+			t.Errorf("want both connections closed: original=%v fake=%v", original.closed, fake.closed)
+		}
+	})
+
+	t.Run("Interface Bindings", func(t *testing.T) {
+		production := Annotate(func() *memoryStore { return &memoryStore{} }, As[Store]())
+
+		r := New().Add(production)
+		r.Replace(func() *memoryStore { return &memoryStore{} })
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) || !strings.Contains(err.Error(), "which also provides bootstrap.Store") {
+			t.Fatalf("want registration error for the unreplaced binding, got %v", err)
+		}
+
+		for _, separately := range []bool{false, true} {
+			fake := &memoryStore{}
+			called := false
+			var store Store
+			var impl *memoryStore
+			r := New().Add(Annotate(func() *memoryStore { called = true; return &memoryStore{} }, As[Store]()), &store, &impl)
+			if separately {
+				r.Replace(func() *memoryStore { return fake })
+				r.Replace(func() Store { return fake })
+			} else {
+				r.Replace(Annotate(func() *memoryStore { return fake }, As[Store]()))
+			}
+
+			if err := r.Run(); err != nil {
+				t.Fatalf("separately=%v: Run failed: %v", separately, err)
+			}
+			if store != fake || impl != fake || called {
+				t.Errorf("separately=%v: want fake for both keys without invoking production (called=%v)", separately, called)
+			}
+			for _, o := range r.Overrides() {
+				if len(o.Replaced) != 1 {
+					t.Errorf("separately=%v: unexpected override %+v", separately, o)
+				}
+			}
+		}
+	})
+
+	t.Run("Nothing Replaced", func(t *testing.T) {
+		r := New()
+		r.Replace(func() *Config { return &Config{} })
+
+		var re *RegistrationError
+		if err := r.Validate(); !errors.As(err, &re) || !strings.Contains(err.Error(), "did not replace anything") {
+			t.Fatalf("want registration error from Validate, got %v", err)
+		}
+		if err := r.Run(); !errors.As(err, &re) {
+			t.Fatalf("want registration error from Run, got %v", err)
+		}
+	})
+
+	t.Run("Replaced Twice", func(t *testing.T) {
+		r := New()
+		r.Add(func() *Config { return &Config{} })
+		r.Replace(func() *Config { return &Config{} }, func() *Config { return &Config{} })
+
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) || !strings.Contains(err.Error(), "already replaced") {
+			t.Fatalf("want registration error, got %v", err)
+		}
+	})
+}
+
+type Redis struct{}
+
+type CacheClient struct {
+	Redis *Redis
+}
+
+func newCacheClient(r *Redis) *CacheClient { return &CacheClient{Redis: r} }
+
+func TestModules(t *testing.T) {
+	t.Run("Nested And Deduplicated", func(t *testing.T) {
+		var invoked int
+		cache := Module{
+			Name:      "cache",
+			Providers: []interface{}{func() *Redis { return &Redis{} }, newCacheClient},
+			Invokes:   []interface{}{func(*CacheClient) { invoked++ }},
+		}
+		api := Module{Name: "api", Modules: []Module{cache}}
+		worker := Module{Name: "worker", Modules: []Module{cache}}
+
+		r := New()
+		var client *CacheClient
+		r.Add(api, &worker, &client)
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if client == nil || client.Redis == nil || invoked != 1 {
+			t.Errorf("want cache registered once, got client %v and %d invocations", client, invoked)
+		}
+	})
+
+	t.Run("Same Name Different Contents", func(t *testing.T) {
+		redis := Module{Name: "db", Providers: []interface{}{func() *Redis { return &Redis{} }}}
+		config := Module{Name: "db", Providers: []interface{}{func() *Config { return &Config{} }}}
+
+		r := New().Add(redis, config)
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) || !strings.Contains(err.Error(), `module "db" registered twice with different contents`) {
+			t.Fatalf("want registration error, got %v", err)
+		}
+
+		// Equal contents in distinct slices are the same module.
+		copied := Module{Name: "db", Providers: []interface{}{redis.Providers[0]}}
+		if err := New().Add(redis, copied).Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	})
+
+	t.Run("Annotated Providers Deduplicated", func(t *testing.T) {
+		cacheModule := func(name string) Module {
+			return Module{Name: "cache", Providers: []interface{}{
+				Annotate(func() *Redis { return &Redis{} }, Private()),
+				Annotate(newCacheClient, Name(name)),
+			}}
+		}
+		api := Module{Name: "api", Modules: []Module{cacheModule("client")}}
+		worker := Module{Name: "worker", Modules: []Module{cacheModule("client")}}
+
+		var client *CacheClient
+		r := New().Add(api, worker, Annotate(&client, Name("client")))
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if client == nil || client.Redis == nil {
+			t.Errorf("want cache client built, got %v", client)
+		}
+
+		worker.Modules = []Module{cacheModule("other")}
+		r = New().Add(api, worker)
+		if err := r.Run(); err == nil || !strings.Contains(err.Error(), "different contents") {
+			t.Fatalf("want modules with different options rejected, got %v", err)
+		}
+	})
+
+	t.Run("Path In Errors", func(t *testing.T) {
+		cache := Module{Name: "cache", Providers: []interface{}{newCacheClient}}
+		api := Module{Name: "api", Modules: []Module{cache}}
+
+		r := New().Add(api)
+		err := r.Run()
+		var missing *MissingDependencyError
+		if !errors.As(err, &missing) {
+			t.Fatalf("want missing dependency, got %v", err)
+		}
+		want := "missing dependency *bootstrap.Redis needed by github.com/viilon/bootstrap.newCacheClient (module: cache ← api)"
+		if err.Error() != want {
+			t.Errorf("want %q, got %q", want, err)
+		}
+		if got := missing.Node.Module; len(got) != 2 || got[0] != "cache" || got[1] != "api" {
+			t.Errorf("unexpected module path %v", got)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cases := map[string]Module{
+			"unnamed":        {Providers: []interface{}{newCacheClient}},
+			"invoke result":  {Name: "m", Invokes: []interface{}{func() *Redis { return nil }}},
+			"bad provider":   {Name: "m", Providers: []interface{}{42}},
+			"nested unnamed": {Name: "m", Modules: []Module{{}}},
+		}
+		for name, m := range cases {
+			var re *RegistrationError
+			if err := New().Add(m).Run(); !errors.As(err, &re) {
+				t.Errorf("%s: want registration error, got %v", name, err)
+			}
+		}
+	})
+}
+
+type redisConfig struct {
+	addr string
+}
+
+func TestPrivateProviders(t *testing.T) {
+	newModule := func(name, addr string) Module {
+		return Module{
+			Name: name,
+			Providers: []interface{}{
+				Annotate(func() *redisConfig { return &redisConfig{addr: addr} }, Private()),
+				Annotate(func(c *redisConfig) *Config { return &Config{Val: c.addr} }, Name(name)),
+			},
+		}
+	}
+
+	t.Run("No Collision Between Modules", func(t *testing.T) {
+		r := New()
+		var cache, queue *Config
+		r.Add(newModule("cache", "cache:6379"), newModule("queue", "queue:6379"))
+		r.Add(Annotate(&cache, Name("cache")), Annotate(&queue, Name("queue")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if cache.Val != "cache:6379" || queue.Val != "queue:6379" {
+			t.Errorf("got %q and %q", cache.Val, queue.Val)
+		}
+	})
+
+	t.Run("Shadows Public Value", func(t *testing.T) {
+		r := New()
+		var outside, inside *Config
+		r.Add(func() *redisConfig { return &redisConfig{addr: "public"} })
+		r.Add(func(c *redisConfig) *Config { return &Config{Val: c.addr} }, &outside)
+		r.Add(newModule("cache", "private"), Annotate(&inside, Name("cache")))
+
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if outside.Val != "public" || inside.Val != "private" {
+			t.Errorf("got %q and %q", outside.Val, inside.Val)
+		}
+	})
+
+	t.Run("Access From Outside", func(t *testing.T) {
+		r := New()
+		var c *redisConfig
+		r.Add(newModule("cache", "cache:6379"), &c)
+
+		err := r.Validate()
+		var private *PrivateProviderError
+		if !errors.As(err, &private) || private.Module != "cache" {
+			t.Fatalf("want private provider error, got %v", err)
+		}
+		if !errors.Is(err, ErrPrivateProvider) || !errors.Is(err, ErrMissingDependency) {
+			t.Errorf("want error matching both sentinels, got %v", err)
+		}
+		if !strings.HasPrefix(err.Error(), "type *bootstrap.redisConfig is private to module cache") {
+			t.Errorf("unexpected message %q", err)
+		}
+	})
+
+	t.Run("Outside Module", func(t *testing.T) {
+		r := New().Add(Annotate(func() *redisConfig { return nil }, Private()))
+
+		var re *RegistrationError
+		if err := r.Run(); !errors.As(err, &re) {
+			t.Fatalf("want registration error, got %v", err)
+		}
+	})
+}
+
+type recordingCleaner struct {
+	name string
+	log  *[]string
+}
+
+func (c *recordingCleaner) Cleanup() error {
+	*c.log = append(*c.log, c.name)
+	return nil
+}
+
+func TestChildContainers(t *testing.T) {
+	t.Run("Inherit And Cleanup Order", func(t *testing.T) {
+		var log []string
+		parent := New()
+		parent.Supply(&Config{Val: "shared"})
+		parent.Add(func() *recordingCleaner { return &recordingCleaner{name: "parent", log: &log} })
+		if err := parent.Run(); err != nil {
+			t.Fatalf("parent Run failed: %v", err)
+		}
+
+		child := parent.Child()
+		var svc *Service
+		var ctx context.Context
+		child.Add(func(c *Config, pc *recordingCleaner) (*Service, *prefixLogger) {
+			return &Service{Cfg: c}, &prefixLogger{prefix: pc.name}
+		})
+		child.Supply(&recordingCleaner{name: "child", log: &log})
+		child.Add(&svc, &ctx)
+		if err := child.Run(); !errors.Is(err, ErrDuplicateProvider) {
+			t.Fatalf("want duplicate provider without Shadow, got %v", err)
+		}
+
+		child = parent.Child()
+		child.Add(func(c *Config, pc *recordingCleaner) *Service {
+			return &Service{Cfg: c}
+		})
+		child.Supply(Annotate(&recordingCleaner{name: "child", log: &log}, Shadow(), Name("child")))
+		child.Add(&svc, &ctx)
+		if err := child.Run(); err != nil {
+			t.Fatalf("child Run failed: %v", err)
+		}
+		if svc.Cfg.Val != "shared" {
+			t.Errorf("want parent config, got %q", svc.Cfg.Val)
+		}
+
+		if err := parent.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if want := []string{"child", "parent"}; !reflect.DeepEqual(log, want) {
+			t.Errorf("want cleanup order %v, got %v", want, log)
+		}
+		if ctx.Err() == nil {
+			t.Error("want child context canceled")
+		}
+	})
+
+	t.Run("Shadow", func(t *testing.T) {
+		parent := New().Supply(&Config{Val: "parent"})
+		if err := parent.Run(); err != nil {
+			t.Fatalf("parent Run failed: %v", err)
+		}
+
+		var childCfg, parentCfg *Config
+		child := parent.Child()
+		child.Add(Annotate(func() *Config { return &Config{Val: "child"} }, Shadow()), &childCfg)
+		child.Decorate(func(c *Config) *Config { return &Config{Val: c.Val + "+decorated"} })
+		if err := child.Run(); err != nil {
+			t.Fatalf("child Run failed: %v", err)
+		}
+		parentCfg, _ = Get[*Config](parent)
+		if childCfg.Val != "child+decorated" || parentCfg.Val != "parent" {
+			t.Errorf("got %q in child and %q in parent", childCfg.Val, parentCfg.Val)
+		}
+	})
+
+	t.Run("Independent Cleanup", func(t *testing.T) {
+		var log []string
+		parent := New()
+		parent.Add(func() *recordingCleaner { return &recordingCleaner{name: "parent", log: &log} })
+		if err := parent.Run(); err != nil {
+			t.Fatalf("parent Run failed: %v", err)
+		}
+
+		child := parent.Child()
+		var pc *recordingCleaner
+		child.Add(&pc)
+		if err := child.Run(); err != nil {
+			t.Fatalf("child Run failed: %v", err)
+		}
+		if err := child.Cleanup(); err != nil {
+			t.Fatalf("child Cleanup failed: %v", err)
+		}
+		if len(log) != 0 {
+			t.Errorf("child must not clean up inherited values, got %v", log)
+		}
+		if err := parent.Cleanup(); err != nil {
+			t.Fatalf("Cleanup failed: %v", err)
+		}
+		if len(log) != 1 {
+			t.Errorf("want parent cleaned up once, got %v", log)
+		}
+	})
+
+	t.Run("Parent Not Run", func(t *testing.T) {
+		parent := New().Supply(&Config{})
+		child := parent.Child()
+		var cfg *Config
+		child.Add(&cfg)
+
+		if err := child.Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		var ce *ConstructorError
+		if err := child.Run(); !errors.As(err, &ce) || !strings.Contains(err.Error(), "run it first") {
+			t.Fatalf("want constructor error, got %v", err)
+		}
+	})
+
+	t.Run("Value Groups", func(t *testing.T) {
+		parent := New().Add(
+			Annotate(func() Migration { return namedMigration("m1") }, Group("migrations")),
+			Annotate(func() Migration { return namedMigration("m2") }, Group("migrations")),
+		)
+		if err := parent.Run(); err != nil {
+			t.Fatalf("parent Run failed: %v", err)
+		}
+
+		child := parent.Child()
+		grandchild := child.Child()
+		var own, inherited []Migration
+		child.Add(
+			Annotate(func() Migration { return namedMigration("c1") }, Group("migrations")),
+			Annotate(&own, Group("migrations")),
+		)
+		grandchild.Add(Annotate(&inherited, Group("migrations")))
+
+		if err := child.Run(); err != nil {
+			t.Fatalf("child Run failed: %v", err)
+		}
+		if err := grandchild.Run(); err != nil {
+			t.Fatalf("grandchild Run failed: %v", err)
+		}
+		names := func(ms []Migration) string {
+			var s []string
+			for _, m := range ms {
+				s = append(s, m.Name())
+			}
+			return strings.Join(s, ",")
+		}
+		if got := names(own); got != "m1,m2,c1" {
+			t.Errorf("child: want m1,m2,c1, got %s", got)
+		}
+		if got := names(inherited); got != "m1,m2,c1" {
+			t.Errorf("grandchild: want m1,m2,c1, got %s", got)
+		}
+	})
+}
+
+func TestGraphExport(t *testing.T) {
+	r := New()
+	var cfg *Config
+	r.Add(newBrokenConfig, &cfg)
+	if err := r.Run(); err == nil {
+		t.Fatal("expected Run to fail")
+	}
+
+	dot := r.DOT()
+	for _, want := range []string{
+		`[label="github.com/viilon/bootstrap.newBrokenConfig", style=filled, fillcolor="#ffdddd"`,
+		`[label="populate **bootstrap.Config", style=dashed`,
+		`[label="*bootstrap.Config"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output lacks %q:\n%s", want, dot)
+		}
+	}
+	if mermaid := r.Mermaid(); !strings.Contains(mermaid, "class n2 failed") {
+		t.Errorf("want failed constructor highlighted:\n%s", mermaid)
+	}
+
+	t.Run("Missing Dependency", func(t *testing.T) {
+		r := New().Add(newCacheClient)
+		if dot := r.DOT(); !strings.Contains(dot, `missing0 [label="missing *bootstrap.Redis"`) {
+			t.Errorf("want missing dependency drawn without Run:\n%s", dot)
+		}
+	})
+}
+
+func TestDump(t *testing.T) {
+	r := New()
+	r.Add(Module{Name: "cache", Providers: []interface{}{func() *memoryStore { return &memoryStore{} }}})
+	r.Supply(&Config{})
+	var store *memoryStore
+	r.Add(&store)
+
+	d, err := r.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	ids := make(map[string]dag.ProviderInfo)
+	for _, p := range d.Providers {
+		ids[p.ID] = p
+	}
+	for _, id := range []string{
+		"default context.Context",
+		"cache: bootstrap_test.go func() *bootstrap.memoryStore",
+		"supplied *bootstrap.Config (bootstrap_test.go)",
+		"populate **bootstrap.memoryStore",
+	} {
+		if _, ok := ids[id]; !ok {
+			t.Errorf("missing provider %q in %v", id, ids)
+		}
+	}
+	if p := ids["cache: bootstrap_test.go func() *bootstrap.memoryStore"]; !p.Cleanup || len(p.Module) != 1 {
+		t.Errorf("want cleaned up provider in module cache, got %+v", p)
+	}
+	if p := ids["supplied *bootstrap.Config (bootstrap_test.go)"]; p.Cleanup || p.Line == 0 {
+		t.Errorf("want supplied value without cleanup at a known line, got %+v", p)
+	}
+
+	r.Add(newCacheClient)
+	if _, err := r.Dump(); !errors.Is(err, ErrMissingDependency) {
+		t.Errorf("want missing dependency, got %v", err)
+	}
+}
+
+func newServiceFromConfig(c *Config) *Service { return &Service{Cfg: c} }
+
+func newLoggerForService(s *Service) *prefixLogger { return &prefixLogger{prefix: s.Cfg.Val} }
+
+func decorateService(s *Service) *Service { return s }
+
+func TestIntrospection(t *testing.T) {
+	r := New()
+	r.Supply(&Config{Val: "cfg"})
+	r.Add(newServiceFromConfig, newLoggerForService)
+	r.Add(Annotate(func() Migration { return namedMigration("m1") }, Group("migrations")))
+	r.Decorate(decorateService)
+
+	ids := func(infos []dag.ProviderInfo) []string {
+		var result []string
+		for _, p := range infos {
+			result = append(result, p.ID)
+		}
+		return result
+	}
+	const (
+		service   = "github.com/viilon/bootstrap.newServiceFromConfig"
+		logger    = "github.com/viilon/bootstrap.newLoggerForService"
+		decorator = "github.com/viilon/bootstrap.decorateService"
+		config    = "supplied *bootstrap.Config (bootstrap_test.go)"
+	)
+
+	providers := r.Providers()
+	if len(providers) != 7 {
+		t.Fatalf("want 7 providers, got %v", ids(providers))
+	}
+	if p := providers[3]; p.ID != service || p.Inputs[0].Type != "*bootstrap.Config" ||
+		p.Outputs[0].Type != "*bootstrap.Service" || !strings.HasSuffix(p.File, "bootstrap_test.go") {
+		t.Errorf("unexpected provider %+v", p)
+	}
+
+	serviceType := reflect.TypeOf((*Service)(nil))
+	if got := ids(r.Producers(serviceType)); !reflect.DeepEqual(got, []string{service, decorator}) {
+		t.Errorf("Producers = %v", got)
+	}
+	if got := ids(r.Producers(reflect.TypeOf([]Migration(nil)), Group("migrations"))); len(got) != 1 {
+		t.Errorf("Producers of group = %v", got)
+	}
+	if got := ids(r.Dependencies(reflect.TypeOf((*prefixLogger)(nil)))); !reflect.DeepEqual(got, []string{config, service, decorator}) {
+		t.Errorf("Dependencies = %v", got)
+	}
+	if got := ids(r.Dependents(reflect.TypeOf((*Config)(nil)))); !reflect.DeepEqual(got, []string{service, logger, decorator}) {
+		t.Errorf("Dependents = %v", got)
+	}
+
+	if values := r.Values(); len(values) != 0 {
+		t.Errorf("want no values before Run, got %v", values)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	built := make(map[string]BuiltValue)
+	for _, v := range r.Values() {
+		built[v.Key.String()] = v
+	}
+	if v := built["*bootstrap.Service"]; v.Provider != decorator || v.Value.(*Service).Cfg.Val != "cfg" {
+		t.Errorf("unexpected service value %+v", v)
+	}
+	if v := built[`bootstrap.Migration[group="migrations"]`]; v.Value != namedMigration("m1") {
+		t.Errorf("unexpected group member %+v", v)
+	}
+	if _, ok := built["context.Context"]; !ok {
+		t.Errorf("want default context listed, got %v", built)
+	}
+}
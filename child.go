@@ -0,0 +1,206 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Child creates a container for a sub-application sharing the values of b, such as an
+// admin server next to the public API. Constructors of the child may depend on any value
+// the parent provides; it is taken from the parent when the child runs, so the parent must
+// have run first. A value group consumed by the child holds the members built by its
+// ancestors, followed by the members the child provides itself.
+//
+// The child has its own context, derived from the parent's, and its own lifecycle. It
+// copies the concurrency, timeout and cleanup settings of the parent. Providing a value
+// the parent already provides is a DuplicateProviderError unless the provider is
+// annotated with Shadow.
+//
+// The child runs, starts and shuts down independently. Shutting down the parent shuts
+// down its children first, in reverse order of creation. Inherited values are only
+// cleaned up by the parent.
+func (b *Bootstrap) Child() *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := newBootstrap(b.ctx)
+	c.parent = b
+	c.workers = b.workers
+	c.lifecycle.startTimeout = b.lifecycle.startTimeout
+	c.lifecycle.stopTimeout = b.lifecycle.stopTimeout
+	c.noAutoCleanup = b.noAutoCleanup
+	c.skipCleanup = append([]reflect.Type(nil), b.skipCleanup...)
+	b.children = append(b.children, c)
+	return c
+}
+
+// shutdownChildren shuts down the children of b in reverse order of creation.
+func (b *Bootstrap) shutdownChildren(ctx context.Context) error {
+	b.mu.RLock()
+	children := b.children
+	b.mu.RUnlock()
+
+	var errs []error
+	for i := len(children) - 1; i >= 0; i-- {
+		if err := children[i].Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// graphNodes returns the providers of b, preceded by a synthetic provider for every
+// value and value group the child b inherits from its ancestors. The synthetic providers are also
+// returned as a set.
+func (b *Bootstrap) graphNodes() ([]*dag.Node, map[*dag.Node]bool, error) {
+	inherited := make(map[*dag.Node]bool)
+	if b.parent == nil {
+		return b.providers, inherited, nil
+	}
+
+	// Values produced by b itself are never inherited.
+	own := make(map[dag.Key]bool)
+	for _, p := range b.providers {
+		for _, out := range p.Outputs {
+			if p.Decorator || out.Group != "" || out.Module != "" {
+				continue
+			}
+			own[out.Key] = true
+			if first := b.parent.producer(out.Key); first != nil && !b.shadowing[p] {
+				return nil, nil, &DuplicateProviderError{Key: out.Key, First: first, Second: p}
+			}
+		}
+	}
+
+	var nodes []*dag.Node
+	for _, p := range b.providers {
+		for _, in := range p.Inputs {
+			if own[in.Key] {
+				continue
+			}
+
+			var n *dag.Node
+			var err error
+			if in.Group != "" {
+				if b.parent.producer(in.Key.Elem()) == nil {
+					continue
+				}
+				n, err = b.inheritGroup(in.Key)
+			} else {
+				if b.parent.producer(in.Key) == nil {
+					continue
+				}
+				n, err = b.inherit(in.Key)
+			}
+			own[in.Key] = true
+			if err != nil {
+				return nil, nil, err
+			}
+			inherited[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	return append(nodes, b.providers...), inherited, nil
+}
+
+// inherit returns a provider of the value key taken from the parent of b.
+func (b *Bootstrap) inherit(key dag.Key) (*dag.Node, error) {
+	parent := b.parent
+	// Create synthetic function: func() (T, error)
+	fnType := reflect.FuncOf(nil, []reflect.Type{key.Type, reflect.TypeOf((*error)(nil)).Elem()}, false)
+	fn := reflect.MakeFunc(fnType, func([]reflect.Value) []reflect.Value {
+		val, ok := parent.lookup(key)
+		if !ok {
+			err := fmt.Errorf("%v has not been built by the parent container; run it first", key)
+			return []reflect.Value{reflect.Zero(key.Type), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{val, reflect.Zero(fnType.Out(1))}
+	})
+
+	n, err := dag.NewNode(fn.Interface())
+	if err != nil {
+		return nil, err
+	}
+	n.Label = fmt.Sprintf("%v inherited from parent", key)
+	n.Synthetic = true
+	n.Outputs[0].Key = key
+	return n, nil
+}
+
+// inheritGroup returns a provider of the members of the value group key built by the
+// ancestors of b. Its single output holds all of them; store adds each as a member.
+func (b *Bootstrap) inheritGroup(key dag.Key) (*dag.Node, error) {
+	parent := b.parent
+	// Create synthetic function: func() []T
+	fnType := reflect.FuncOf(nil, []reflect.Type{key.Type}, false)
+	fn := reflect.MakeFunc(fnType, func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{parent.lookupGroup(key)}
+	})
+
+	n, err := dag.NewNode(fn.Interface())
+	if err != nil {
+		return nil, err
+	}
+	n.Label = fmt.Sprintf("%v inherited from parent", key)
+	n.Synthetic = true
+	n.Outputs[0].Key = key.Elem()
+	return n, nil
+}
+
+// producer returns the public provider of key in b or its ancestors, or nil.
+func (b *Bootstrap) producer(key dag.Key) *dag.Node {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, p := range b.providers {
+		if p.Decorator {
+			continue
+		}
+		for _, out := range p.Outputs {
+			if out.Key == key {
+				return p
+			}
+		}
+	}
+	if b.parent != nil {
+		return b.parent.producer(key)
+	}
+	return nil
+}
+
+// lookup returns the value of key built by b or its ancestors.
+func (b *Bootstrap) lookup(key dag.Key) (reflect.Value, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if val, ok := b.values[key]; ok {
+		return val, true
+	}
+	if b.parent != nil {
+		return b.parent.lookup(key)
+	}
+	return reflect.Value{}, false
+}
+
+// lookupGroup returns the members of the value group key built by b and its ancestors,
+// ancestors first.
+func (b *Bootstrap) lookupGroup(key dag.Key) reflect.Value {
+	slice := reflect.MakeSlice(key.Type, 0, 0)
+	if b.parent != nil {
+		slice = b.parent.lookupGroup(key)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Members inherited by b are already included.
+	members := b.groups[key.Elem()]
+	for _, p := range b.providers {
+		slice = reflect.Append(slice, members[p]...)
+	}
+	return slice
+}
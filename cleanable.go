@@ -1,6 +1,141 @@
 package bootstrap
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
 // Cleanable is the interface that groups the basic Cleanup method.
 type Cleanable interface {
 	Cleanup() error
 }
+
+// ContextCleanable is implemented by components whose cleanup honors a deadline.
+// It takes precedence over Cleanable when a component implements both.
+type ContextCleanable interface {
+	CleanupContext(ctx context.Context) error
+}
+
+// cleanup is a registered cleanup hook together with the component it belongs to.
+type cleanup struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Conventional cleanup methods of third-party types, recognized when auto-detection is enabled.
+type (
+	shutdowner interface {
+		Shutdown(ctx context.Context) error
+	}
+	closerNoError interface {
+		Close()
+	}
+	stopper interface {
+		Stop()
+	}
+)
+
+// cleanupFunc returns the cleanup hook implemented by v, if any. Cleanable and
+// ContextCleanable are always honored; when auto is set, Shutdown(ctx) error,
+// io.Closer, Close() and Stop() are recognized as well, in that order of preference.
+func cleanupFunc(v interface{}, auto bool) (func(context.Context) error, bool) {
+	switch c := v.(type) {
+	case ContextCleanable:
+		return c.CleanupContext, true
+	case Cleanable:
+		return func(context.Context) error { return c.Cleanup() }, true
+	}
+
+	if !auto {
+		return nil, false
+	}
+
+	switch c := v.(type) {
+	case shutdowner:
+		return c.Shutdown, true
+	case io.Closer:
+		return func(context.Context) error { return c.Close() }, true
+	case closerNoError:
+		return func(context.Context) error { c.Close(); return nil }, true
+	case stopper:
+		return func(context.Context) error { c.Stop(); return nil }, true
+	}
+	return nil, false
+}
+
+// cleanableType reports whether every value of type t has a cleanup hook, as detected
+// by cleanupFunc. Interface types only qualify when the interface itself requires it.
+func cleanableType(t reflect.Type, auto bool) bool {
+	hooks := []reflect.Type{
+		reflect.TypeOf((*ContextCleanable)(nil)).Elem(),
+		reflect.TypeOf((*Cleanable)(nil)).Elem(),
+	}
+	if auto {
+		hooks = append(hooks,
+			reflect.TypeOf((*shutdowner)(nil)).Elem(),
+			reflect.TypeOf((*io.Closer)(nil)).Elem(),
+			reflect.TypeOf((*closerNoError)(nil)).Elem(),
+			reflect.TypeOf((*stopper)(nil)).Elem(),
+		)
+	}
+	for _, hook := range hooks {
+		if t.Implements(hook) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoCleanup reports whether conventional cleanup methods of t are detected.
+func (b *Bootstrap) autoCleanup(t reflect.Type) bool {
+	if b.noAutoCleanup {
+		return false
+	}
+	for _, skip := range b.skipCleanup {
+		if t == skip || (skip.Kind() == reflect.Interface && t.Implements(skip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// returnedCleanup adapts a func() or func() error returned by a constructor.
+func returnedCleanup(fn interface{}) func(context.Context) error {
+	switch f := fn.(type) {
+	case func():
+		return func(context.Context) error { f(); return nil }
+	case func() error:
+		return func(context.Context) error { return f() }
+	}
+	// Named function types are converted through reflection.
+	v := reflect.ValueOf(fn)
+	return func(context.Context) error {
+		if out := v.Call(nil); len(out) == 1 && !out[0].IsNil() {
+			return out[0].Interface().(error)
+		}
+		return nil
+	}
+}
+
+// runCleanups executes cleanups in reverse order and aggregates their errors.
+// Each cleanup is bounded by ctx and timeout; one that does not return in time is
+// reported with a CleanupError wrapping the context error, and the remaining
+// cleanups still run.
+func runCleanups(ctx context.Context, timeout time.Duration, cleanups []cleanup) error {
+	var errs []error
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		c := cleanups[i]
+		if err := runWithTimeout(ctx, timeout, c.fn); err != nil {
+			errs = append(errs, &CleanupError{Component: c.name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cleanup errors: %w", errors.Join(errs...))
+	}
+	return nil
+}
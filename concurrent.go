@@ -0,0 +1,101 @@
+package bootstrap
+
+import (
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// outcome is the result of a constructor call made by a worker goroutine.
+type outcome struct {
+	node    *dag.Node
+	results []reflect.Value
+	err     error
+}
+
+// executeConcurrently runs every node as soon as all of its dependencies have been built,
+// with at most b.workers constructors in flight. Only the calling goroutine reads and
+// writes the container, so values and cleanups need no extra locking, and cleanups are
+// registered in completion order, which always places dependencies first.
+func (b *Bootstrap) executeConcurrently(g *dag.Graph) error {
+	pending := make(map[*dag.Node]int)
+	dependents := make(map[*dag.Node][]*dag.Node)
+	for _, n := range g.Nodes {
+		seen := make(map[*dag.Node]bool)
+		for _, d := range g.Deps[n] {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			pending[n]++
+			dependents[d] = append(dependents[d], n)
+		}
+	}
+
+	var ready []*dag.Node
+	for _, n := range g.Nodes {
+		if pending[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	var (
+		done     = make(chan outcome)
+		running  int
+		firstErr error
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			b.cancelRun()
+		}
+	}
+
+	for {
+		// Start ready nodes until the worker limit is reached; stop starting
+		// new ones once a constructor has failed.
+		for firstErr == nil && running < b.workers && len(ready) > 0 {
+			p := ready[0]
+			ready = ready[1:]
+
+			args, err := b.arguments(p)
+			if err != nil {
+				fail(err)
+				break
+			}
+
+			running++
+			go func() {
+				done <- invoke(p, args)
+			}()
+		}
+
+		if running == 0 {
+			return firstErr
+		}
+
+		// Components that finish after a failure are still stored,
+		// so that their cleanups take part in the rollback.
+		o := <-done
+		running--
+		if o.err != nil {
+			fail(o.err)
+			continue
+		}
+		b.store(o.node, o.results)
+
+		for _, d := range dependents[o.node] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+}
+
+// invoke calls the constructor of p from a worker goroutine.
+func invoke(p *dag.Node, args []reflect.Value) (o outcome) {
+	o.node = p
+	o.results, o.err = call(p, args)
+	return o
+}
@@ -0,0 +1,244 @@
+package dag
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Dump is a machine-readable description of a resolved graph, meant to be serialized
+// with encoding/json. Providers are listed in registration order.
+type Dump struct {
+	Providers []ProviderInfo `json:"providers"`
+	Edges     []EdgeInfo     `json:"edges"`
+}
+
+// ProviderInfo describes a node of a Dump.
+type ProviderInfo struct {
+	// ID identifies the provider across builds. It is derived from its label, with
+	// source locations reduced to file names, and its module path. Providers sharing
+	// an ID are numbered in registration order.
+	ID           string       `json:"id"`
+	Label        string       `json:"label"`
+	File         string       `json:"file,omitempty"`
+	Line         int          `json:"line,omitempty"`
+	Inputs       []InputInfo  `json:"inputs"`
+	Outputs      []OutputInfo `json:"outputs"`
+	ErrorIndices []int        `json:"error_indices"`
+	// Cleanup reports whether the provider returns a cleanup function. Callers that
+	// know more about the outputs, such as Bootstrap.Dump, may set it for outputs
+	// that are cleaned up as well.
+	Cleanup   bool     `json:"cleanup"`
+	Module    []string `json:"module,omitempty"`
+	Synthetic bool     `json:"synthetic,omitempty"`
+	Decorator bool     `json:"decorator,omitempty"`
+	// Order is the position of the provider in execution order, or -1 when the
+	// graph cannot be resolved.
+	Order int `json:"order"`
+}
+
+// KeyInfo describes a Key.
+type KeyInfo struct {
+	Type   string `json:"type"`
+	Name   string `json:"name,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Module string `json:"module,omitempty"`
+}
+
+// InputInfo describes an Input.
+type InputInfo struct {
+	KeyInfo
+	Optional bool `json:"optional,omitempty"`
+}
+
+// OutputInfo describes an Output.
+type OutputInfo struct {
+	KeyInfo
+	Index int `json:"index"`
+}
+
+// EdgeInfo describes an Edge by the IDs of its providers.
+type EdgeInfo struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Key  string `json:"key"`
+}
+
+func (e EdgeInfo) String() string {
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Key)
+}
+
+// NewDump resolves nodes like Build and describes the result.
+func NewDump(nodes []*Node) (*Dump, error) {
+	g, err := Build(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := stableIDs(nodes)
+	d := &Dump{
+		Providers: describe(nodes, ids, g),
+		Edges:     make([]EdgeInfo, 0, len(g.Edges)),
+	}
+	for _, e := range g.Edges {
+		d.Edges = append(d.Edges, EdgeInfo{From: ids[e.From], To: ids[e.To], Key: e.Key.String()})
+	}
+	return d, nil
+}
+
+// Describe describes each of nodes, without requiring the graph to be valid.
+// When it is not, every Order is -1.
+func Describe(nodes []*Node) []ProviderInfo {
+	g, _ := Build(nodes)
+	return describe(nodes, stableIDs(nodes), g)
+}
+
+func describe(nodes []*Node, ids map[*Node]string, g *Graph) []ProviderInfo {
+	order := make(map[*Node]int, len(nodes))
+	if g != nil {
+		for i, n := range g.Nodes {
+			order[n] = i
+		}
+	}
+
+	providers := make([]ProviderInfo, 0, len(nodes))
+	for _, n := range nodes {
+		p := ProviderInfo{
+			ID:           ids[n],
+			Label:        nodeLabel(n),
+			File:         n.File,
+			Line:         n.Line,
+			Inputs:       make([]InputInfo, 0, len(n.Inputs)),
+			Outputs:      make([]OutputInfo, 0, len(n.Outputs)),
+			ErrorIndices: append([]int{}, n.ErrorIndices...),
+			Cleanup:      n.CleanupIndex >= 0,
+			Module:       slices.Clone(n.Module),
+			Synthetic:    n.Synthetic,
+			Decorator:    n.Decorator,
+			Order:        -1,
+		}
+		if i, ok := order[n]; ok {
+			p.Order = i
+		}
+		for _, in := range n.Inputs {
+			p.Inputs = append(p.Inputs, InputInfo{KeyInfo: keyInfo(in.Key), Optional: in.Optional})
+		}
+		for _, out := range n.Outputs {
+			p.Outputs = append(p.Outputs, OutputInfo{KeyInfo: keyInfo(out.Key), Index: out.Index})
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+func keyInfo(k Key) KeyInfo {
+	return KeyInfo{Type: k.Type.String(), Name: k.Name, Group: k.Group, Module: k.Module}
+}
+
+var sourceLocation = regexp.MustCompile(`([^\s(]+\.go):\d+`)
+
+// stableIDs assigns every node an ID that does not depend on absolute paths,
+// line numbers or the addresses of nodes.
+func stableIDs(nodes []*Node) map[*Node]string {
+	ids := make(map[*Node]string, len(nodes))
+	seen := make(map[string]int)
+	for _, n := range nodes {
+		label := n.Label
+		if label == "" {
+			label = FuncLabel(n.Fn)
+		}
+		id := sourceLocation.ReplaceAllStringFunc(label, func(loc string) string {
+			return filepath.Base(sourceLocation.FindStringSubmatch(loc)[1])
+		})
+		if id != label && !strings.Contains(label, " ") {
+			// An anonymous function is told apart by its signature.
+			id += " " + n.Fn.Type().String()
+		}
+		if len(n.Module) > 0 {
+			path := slices.Clone(n.Module)
+			slices.Reverse(path)
+			id = strings.Join(path, "/") + ": " + id
+		}
+
+		seen[id]++
+		if c := seen[id]; c > 1 {
+			id = fmt.Sprintf("%s #%d", id, c)
+		}
+		ids[n] = id
+	}
+	return ids
+}
+
+// DumpDiff lists the differences between two dumps.
+type DumpDiff struct {
+	AddedProviders   []string   `json:"added_providers,omitempty"`
+	RemovedProviders []string   `json:"removed_providers,omitempty"`
+	AddedEdges       []EdgeInfo `json:"added_edges,omitempty"`
+	RemovedEdges     []EdgeInfo `json:"removed_edges,omitempty"`
+}
+
+// DiffDumps compares the providers and edges of two dumps by ID.
+func DiffDumps(before, after *Dump) *DumpDiff {
+	diff := &DumpDiff{}
+
+	beforeIDs := make(map[string]bool, len(before.Providers))
+	for _, p := range before.Providers {
+		beforeIDs[p.ID] = true
+	}
+	afterIDs := make(map[string]bool, len(after.Providers))
+	for _, p := range after.Providers {
+		afterIDs[p.ID] = true
+		if !beforeIDs[p.ID] {
+			diff.AddedProviders = append(diff.AddedProviders, p.ID)
+		}
+	}
+	for _, p := range before.Providers {
+		if !afterIDs[p.ID] {
+			diff.RemovedProviders = append(diff.RemovedProviders, p.ID)
+		}
+	}
+
+	beforeEdges := make(map[EdgeInfo]bool, len(before.Edges))
+	for _, e := range before.Edges {
+		beforeEdges[e] = true
+	}
+	afterEdges := make(map[EdgeInfo]bool, len(after.Edges))
+	for _, e := range after.Edges {
+		afterEdges[e] = true
+		if !beforeEdges[e] {
+			diff.AddedEdges = append(diff.AddedEdges, e)
+		}
+	}
+	for _, e := range before.Edges {
+		if !afterEdges[e] {
+			diff.RemovedEdges = append(diff.RemovedEdges, e)
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the dumps compared were equivalent.
+func (d *DumpDiff) Empty() bool {
+	return len(d.AddedProviders) == 0 && len(d.RemovedProviders) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0
+}
+
+// String lists the differences one per line, prefixed with + or -.
+func (d *DumpDiff) String() string {
+	var sb strings.Builder
+	for _, id := range d.AddedProviders {
+		fmt.Fprintf(&sb, "+ provider %s\n", id)
+	}
+	for _, id := range d.RemovedProviders {
+		fmt.Fprintf(&sb, "- provider %s\n", id)
+	}
+	for _, e := range d.AddedEdges {
+		fmt.Fprintf(&sb, "+ edge %s\n", e)
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(&sb, "- edge %s\n", e)
+	}
+	return sb.String()
+}
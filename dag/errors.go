@@ -0,0 +1,80 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors matched by the typed errors below via errors.Is.
+var (
+	ErrMissingDependency = errors.New("missing dependency")
+	ErrDuplicateProvider = errors.New("duplicate provider")
+	ErrCycle             = errors.New("cyclic dependence")
+	ErrPrivateProvider   = errors.New("private provider")
+)
+
+// MissingDependencyError reports an input of Node that no provider produces.
+type MissingDependencyError struct {
+	Key  Key
+	Node *Node
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("missing dependency %v needed by %s", e.Key, nodeLabel(e.Node))
+}
+
+func (e *MissingDependencyError) Is(target error) bool {
+	return target == ErrMissingDependency
+}
+
+// PrivateProviderError reports an input of Node that is only produced privately
+// by another module. It matches both ErrPrivateProvider and ErrMissingDependency.
+type PrivateProviderError struct {
+	Key    Key
+	Module string
+	Node   *Node
+}
+
+func (e *PrivateProviderError) Error() string {
+	return fmt.Sprintf("type %v is private to module %s, needed by %s", e.Key, e.Module, nodeLabel(e.Node))
+}
+
+func (e *PrivateProviderError) Is(target error) bool {
+	return target == ErrPrivateProvider || target == ErrMissingDependency
+}
+
+// DuplicateProviderError reports two nodes producing the same key.
+type DuplicateProviderError struct {
+	Key    Key
+	First  *Node
+	Second *Node
+}
+
+func (e *DuplicateProviderError) Error() string {
+	return fmt.Sprintf("duplicate provider for type %v: %s and %s", e.Key, nodeLabel(e.First), nodeLabel(e.Second))
+}
+
+func (e *DuplicateProviderError) Is(target error) bool {
+	return target == ErrDuplicateProvider
+}
+
+// CycleError reports a dependency cycle. Path starts and ends with the same node.
+// When reported by Validate, Component lists every node of the strongly
+// connected component the cycle belongs to.
+type CycleError struct {
+	Path      []*Node
+	Component []*Node
+}
+
+func (e *CycleError) Error() string {
+	parts := make([]string, 0, len(e.Path))
+	for _, p := range e.Path {
+		parts = append(parts, nodeLabel(p))
+	}
+	return "cyclic dependence: " + strings.Join(parts, " -> ")
+}
+
+func (e *CycleError) Is(target error) bool {
+	return target == ErrCycle
+}
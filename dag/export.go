@@ -0,0 +1,198 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NodeError is implemented by errors attributed to a single node, such as a
+// constructor that failed. DOT and Mermaid highlight the node.
+type NodeError interface {
+	error
+	FailedNode() *Node
+}
+
+// DOT renders the dependency graph of nodes in the Graphviz DOT language. Edges point
+// from a producer to its consumer and are labelled with the key they carry. Synthetic
+// nodes are dashed. When failure is the error of a failed Resolve, Validate or run,
+// the nodes it reports are highlighted in red, and missing dependencies are drawn as
+// placeholder nodes.
+func DOT(nodes []*Node, failure error) string {
+	d := newDiagram(nodes, failure)
+
+	var sb strings.Builder
+	sb.WriteString("digraph bootstrap {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box];\n")
+	for _, n := range d.nodes {
+		var attrs []string
+		attrs = append(attrs, "label="+strconv.Quote(n.label))
+		switch {
+		case n.missing:
+			attrs = append(attrs, `shape=ellipse`, `style="dashed,filled"`, `fillcolor="#ffdddd"`, `color="#cc0000"`)
+		case n.failed:
+			attrs = append(attrs, `style=filled`, `fillcolor="#ffdddd"`, `color="#cc0000"`, `penwidth=2`)
+		case n.synthetic:
+			attrs = append(attrs, `style=dashed`, `color=gray40`)
+		}
+		fmt.Fprintf(&sb, "\t%s [%s];\n", n.id, strings.Join(attrs, ", "))
+	}
+	for _, e := range d.edges {
+		attrs := "label=" + strconv.Quote(e.label)
+		if e.missing {
+			attrs += `, style=dashed, color="#cc0000"`
+		}
+		fmt.Fprintf(&sb, "\t%s -> %s [%s];\n", e.from, e.to, attrs)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the dependency graph of nodes as a Mermaid flowchart, with the same
+// conventions as DOT: synthetic nodes use the "synthetic" class, failed nodes the
+// "failed" class, and placeholders for missing dependencies the "missing" class.
+func Mermaid(nodes []*Node, failure error) string {
+	d := newDiagram(nodes, failure)
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	var synthetic, failed, missing []string
+	for _, n := range d.nodes {
+		switch {
+		case n.missing:
+			fmt.Fprintf(&sb, "\t%s{{%s}}\n", n.id, mermaidText(n.label))
+			missing = append(missing, n.id)
+		case n.synthetic:
+			fmt.Fprintf(&sb, "\t%s([%s])\n", n.id, mermaidText(n.label))
+			synthetic = append(synthetic, n.id)
+		default:
+			fmt.Fprintf(&sb, "\t%s[%s]\n", n.id, mermaidText(n.label))
+		}
+		if n.failed {
+			failed = append(failed, n.id)
+		}
+	}
+	for _, e := range d.edges {
+		arrow := "-->"
+		if e.missing {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&sb, "\t%s %s|%s| %s\n", e.from, arrow, mermaidText(e.label), e.to)
+	}
+
+	sb.WriteString("\tclassDef synthetic stroke-dasharray: 5 5,color:#666\n")
+	sb.WriteString("\tclassDef failed fill:#ffdddd,stroke:#cc0000,stroke-width:2px\n")
+	sb.WriteString("\tclassDef missing fill:#ffdddd,stroke:#cc0000,stroke-dasharray: 5 5\n")
+	for _, c := range []struct {
+		name string
+		ids  []string
+	}{{"synthetic", synthetic}, {"failed", failed}, {"missing", missing}} {
+		if len(c.ids) > 0 {
+			fmt.Fprintf(&sb, "\tclass %s %s\n", strings.Join(c.ids, ","), c.name)
+		}
+	}
+	return sb.String()
+}
+
+// mermaidText quotes a label, replacing the characters Mermaid cannot escape.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+type diagramNode struct {
+	id        string
+	label     string
+	synthetic bool
+	failed    bool
+	missing   bool
+}
+
+type diagramEdge struct {
+	from, to string
+	label    string
+	missing  bool
+}
+
+type diagram struct {
+	nodes []*diagramNode
+	edges []diagramEdge
+}
+
+// newDiagram lays out nodes and their edges, marking the problems reported by failure.
+func newDiagram(nodes []*Node, failure error) *diagram {
+	d := &diagram{}
+	ids := make(map[*Node]*diagramNode, len(nodes))
+	for i, n := range nodes {
+		dn := &diagramNode{id: fmt.Sprintf("n%d", i), label: nodeLabel(n), synthetic: n.Synthetic}
+		ids[n] = dn
+		d.nodes = append(d.nodes, dn)
+	}
+
+	_, edges, _ := buildDeps(nodes, true)
+	for _, e := range edges {
+		d.edges = append(d.edges, diagramEdge{from: ids[e.From].id, to: ids[e.To].id, label: e.Key.String()})
+	}
+
+	fail := func(n *Node) {
+		if dn, ok := ids[n]; ok {
+			dn.failed = true
+		}
+	}
+	// The same problem may be reported by several errors, so placeholders
+	// and their edges are shared.
+	placeholders := make(map[string]*diagramNode)
+	drawn := make(map[diagramEdge]bool)
+	missing := func(n *Node, key Key, label string) {
+		to, ok := ids[n]
+		if !ok {
+			return
+		}
+		dn := placeholders[label]
+		if dn == nil {
+			dn = &diagramNode{id: fmt.Sprintf("missing%d", len(placeholders)), label: label, missing: true}
+			placeholders[label] = dn
+			d.nodes = append(d.nodes, dn)
+		}
+		e := diagramEdge{from: dn.id, to: to.id, label: key.String(), missing: true}
+		if !drawn[e] {
+			drawn[e] = true
+			d.edges = append(d.edges, e)
+		}
+	}
+	walkErrors(failure, func(err error) {
+		switch e := err.(type) {
+		case *MissingDependencyError:
+			missing(e.Node, e.Key, "missing "+e.Key.String())
+		case *PrivateProviderError:
+			missing(e.Node, e.Key, fmt.Sprintf("%v private to module %s", e.Key, e.Module))
+		case *DuplicateProviderError:
+			fail(e.First)
+			fail(e.Second)
+		case *CycleError:
+			for _, n := range e.Path {
+				fail(n)
+			}
+		case NodeError:
+			fail(e.FailedNode())
+		}
+	})
+	return d
+}
+
+// walkErrors calls fn for err and every error it wraps, including joined errors.
+func walkErrors(err error, fn func(error)) {
+	if err == nil {
+		return
+	}
+	fn(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			walkErrors(inner, fn)
+		}
+	default:
+		walkErrors(errors.Unwrap(err), fn)
+	}
+}
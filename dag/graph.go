@@ -5,39 +5,175 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// Graph is a resolved dependency graph.
+type Graph struct {
+	// Nodes holds every node in topological order.
+	Nodes []*Node
+	// Deps maps each node to the nodes producing its inputs.
+	Deps map[*Node][]*Node
+	// Edges lists every dependency together with the key it carries.
+	Edges []Edge
+}
+
+// Edge is a dependency of To on a value of From. Key is the input of To,
+// which is a slice key for value groups.
+type Edge struct {
+	From *Node
+	To   *Node
+	Key  Key
+}
+
 // Resolve builds the dependency graph, checks for missing dependencies and cycles,
-// and returns the nodes in topological order.
+// and returns the nodes in topological order. It stops at the first problem found;
+// use Validate to report all of them.
 func Resolve(nodes []*Node) ([]*Node, error) {
-	// 1. Map outputs to producers
-	producers := make(map[reflect.Type]*Node)
+	g, err := Build(nodes)
+	if err != nil {
+		return nil, err
+	}
+	return g.Nodes, nil
+}
+
+// Build is like Resolve but also returns the dependency edges between nodes.
+func Build(nodes []*Node) (*Graph, error) {
+	deps, edges, errs := buildDeps(nodes, false)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	// Topological Sort (includes cycle detection)
+	sorted, err := topologicalSort(nodes, deps)
+	if err != nil {
+		return nil, err
+	}
+	return &Graph{Nodes: sorted, Deps: deps, Edges: edges}, nil
+}
+
+// Validate walks the whole graph and reports every problem at once: each missing
+// dependency with the node that needs it, each duplicate provider pair, and each
+// strongly connected component that forms a cycle. The result is joined with
+// errors.Join, so individual errors remain reachable through errors.As.
+// Validate returns nil when Resolve would succeed.
+func Validate(nodes []*Node) error {
+	deps, _, errs := buildDeps(nodes, true)
+	for _, component := range stronglyConnected(nodes, deps) {
+		errs = append(errs, &CycleError{
+			Path:      cyclePath(component, deps),
+			Component: component,
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// buildDeps maps every node to the nodes producing its inputs, and lists the
+// corresponding edges. Unless all is set, it returns as soon as the first error is found.
+func buildDeps(nodes []*Node, all bool) (map[*Node][]*Node, []Edge, []error) {
+	var errs []error
+	report := func(err error) bool {
+		errs = append(errs, err)
+		return all
+	}
+
+	// 1. Map outputs to producers. Group members and decorators are collected in registration order.
+	producers := make(map[Key]*Node)
+	groups := make(map[Key][]*Node)
+	decorators := make(map[Key][]*Node)
+	private := make(map[Key]string) // unscoped key -> first module producing it privately
 	for _, n := range nodes {
 		for _, out := range n.Outputs {
-			if existing, ok := producers[out]; ok {
-				return nil, fmt.Errorf("duplicate provider for type %v: %s and %s",
-					out, nodeLabel(existing), nodeLabel(n))
+			if out.Module != "" {
+				public := out.Key
+				public.Module = ""
+				if _, ok := private[public]; !ok {
+					private[public] = out.Module
+				}
+			}
+			if n.Decorator {
+				decorators[out.Key] = append(decorators[out.Key], n)
+				continue
 			}
-			producers[out] = n
+			if out.Group != "" {
+				groups[out.Key] = append(groups[out.Key], n)
+				continue
+			}
+			if existing, ok := producers[out.Key]; ok {
+				if !report(&DuplicateProviderError{Key: out.Key, First: existing, Second: n}) {
+					return nil, nil, errs
+				}
+				continue
+			}
+			producers[out.Key] = n
 		}
 	}
 
 	// 2. Build dependency graph
 	deps := make(map[*Node][]*Node)
+	var edges []Edge
 	for _, n := range nodes {
 		for _, in := range n.Inputs {
-			prod, ok := producers[in]
+			if in.Group != "" {
+				if in.Type.Kind() != reflect.Slice {
+					if !report(fmt.Errorf("group dependency %v in %s must be a slice", in, nodeLabel(n))) {
+						return nil, nil, errs
+					}
+					continue
+				}
+				// An empty group is valid and yields an empty slice.
+				for _, m := range groups[in.Elem()] {
+					deps[n] = append(deps[n], m)
+					edges = append(edges, Edge{From: m, To: n, Key: in.Key})
+				}
+				continue
+			}
+			// Values private to the module of n take precedence over public ones.
+			key := in.Key
+			if m := n.module(); m != "" {
+				scoped := in.Key
+				scoped.Module = m
+				if _, ok := producers[scoped]; ok || len(decorators[scoped]) > 0 {
+					key = scoped
+				}
+			}
+			prod, ok := producers[key]
+			if chain := decorators[key]; len(chain) > 0 {
+				// Consumers see the last decorator; a decorator sees its predecessor.
+				if i := slices.Index(chain, n); i < 0 {
+					prod, ok = chain[len(chain)-1], true
+				} else if i > 0 {
+					prod, ok = chain[i-1], true
+				} else if !ok {
+					// There is nothing to decorate, even if the input is optional.
+					var err error = &MissingDependencyError{Key: in.Key, Node: n}
+					if m, isPrivate := private[in.Key]; isPrivate {
+						err = &PrivateProviderError{Key: in.Key, Module: m, Node: n}
+					}
+					if !report(err) {
+						return nil, nil, errs
+					}
+					continue
+				}
+			}
 			if ok {
 				deps[n] = append(deps[n], prod)
-			} else {
-				return nil, fmt.Errorf("missing dependency for type %v in %s", in, nodeLabel(n))
+				edges = append(edges, Edge{From: prod, To: n, Key: in.Key})
+			} else if m, isPrivate := private[in.Key]; isPrivate && !in.Optional {
+				if !report(&PrivateProviderError{Key: in.Key, Module: m, Node: n}) {
+					return nil, nil, errs
+				}
+			} else if !in.Optional {
+				if !report(&MissingDependencyError{Key: in.Key, Node: n}) {
+					return nil, nil, errs
+				}
 			}
 		}
 	}
 
-	// 3. Topological Sort (includes cycle detection)
-	return topologicalSort(nodes, deps)
+	return deps, edges, errs
 }
 
 func topologicalSort(nodes []*Node, deps map[*Node][]*Node) ([]*Node, error) {
@@ -86,12 +222,9 @@ func checkCycles(nodes []*Node, deps map[*Node][]*Node) error {
 	visit = func(n *Node) error {
 		if idx, ok := inStack[n]; ok {
 			// Construct cycle path: stack[idx:] -> n
-			cycleNodes := append(stack[idx:], n)
-			parts := make([]string, 0, len(cycleNodes))
-			for _, p := range cycleNodes {
-				parts = append(parts, nodeLabel(p))
-			}
-			return errors.New("cyclic dependence: " + strings.Join(parts, " -> "))
+			path := make([]*Node, 0, len(stack)-idx+1)
+			path = append(path, stack[idx:]...)
+			return &CycleError{Path: append(path, n)}
 		}
 		if visited[n] {
 			return nil
@@ -124,8 +257,150 @@ func checkCycles(nodes []*Node, deps map[*Node][]*Node) error {
 	return nil
 }
 
+// stronglyConnected returns every strongly connected component of the graph
+// that forms a cycle, using Tarjan's algorithm. Components and their members
+// are ordered deterministically by node position.
+func stronglyConnected(nodes []*Node, deps map[*Node][]*Node) [][]*Node {
+	var (
+		index      = make(map[*Node]int)
+		lowlink    = make(map[*Node]int)
+		onStack    = make(map[*Node]bool)
+		stack      []*Node
+		components [][]*Node
+		next       int
+		visit      func(*Node)
+	)
+
+	visit = func(n *Node) {
+		index[n] = next
+		lowlink[n] = next
+		next++
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, m := range deps[n] {
+			if _, seen := index[m]; !seen {
+				visit(m)
+				lowlink[n] = min(lowlink[n], lowlink[m])
+			} else if onStack[m] {
+				lowlink[n] = min(lowlink[n], index[m])
+			}
+		}
+
+		if lowlink[n] != index[n] {
+			return
+		}
+
+		var component []*Node
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			component = append(component, m)
+			if m == n {
+				break
+			}
+		}
+
+		// A single node only forms a cycle when it depends on itself.
+		if len(component) == 1 && !contains(deps[n], n) {
+			return
+		}
+		components = append(components, component)
+	}
+
+	for _, n := range nodes {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+
+	position := make(map[*Node]int, len(nodes))
+	for i, n := range nodes {
+		position[n] = i
+	}
+	for _, c := range components {
+		sort.Slice(c, func(i, j int) bool { return position[c[i]] < position[c[j]] })
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return position[components[i][0]] < position[components[j][0]]
+	})
+	return components
+}
+
+// cyclePath returns the shortest cycle through the first node of a strongly
+// connected component, starting and ending with that node.
+func cyclePath(component []*Node, deps map[*Node][]*Node) []*Node {
+	start := component[0]
+	inComponent := make(map[*Node]bool, len(component))
+	for _, n := range component {
+		inComponent[n] = true
+	}
+
+	// Breadth-first search back to start, staying inside the component.
+	parent := make(map[*Node]*Node)
+	queue := []*Node{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, m := range deps[n] {
+			if m == start {
+				path := []*Node{start}
+				for p := n; p != start; p = parent[p] {
+					path = append(path, p)
+				}
+				slices.Reverse(path[1:])
+				return append(path, start)
+			}
+			if _, seen := parent[m]; seen || !inComponent[m] {
+				continue
+			}
+			parent[m] = n
+			queue = append(queue, m)
+		}
+	}
+	return component
+}
+
+func contains(nodes []*Node, n *Node) bool {
+	for _, m := range nodes {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a human readable label for the node's constructor.
+func (n *Node) String() string {
+	return nodeLabel(n)
+}
+
 func nodeLabel(n *Node) string {
-	pc := n.Fn.Pointer()
+	label := n.Label
+	if label == "" {
+		label = FuncLabel(n.Fn)
+	}
+	if len(n.Module) > 0 {
+		label += fmt.Sprintf(" (module: %s)", strings.Join(n.Module, " ← "))
+	}
+	return label
+}
+
+// FuncLocation returns the file and line number where fn is defined. It returns an
+// empty file for functions created with reflect.MakeFunc.
+func FuncLocation(fn reflect.Value) (string, int) {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil || strings.HasPrefix(f.Name(), "reflect.") {
+		return "", 0
+	}
+	return f.FileLine(f.Entry())
+}
+
+// FuncLabel returns a human readable label for a function: its qualified name,
+// or its file and line number for anonymous functions.
+func FuncLabel(fn reflect.Value) string {
+	pc := fn.Pointer()
 	f := runtime.FuncForPC(pc)
 	if f == nil {
 		return "unknown"
@@ -0,0 +1,301 @@
+package dag
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type (
+	a struct{}
+	b struct{}
+	c struct{}
+	d struct{}
+)
+
+func mustNodes(t *testing.T, fns ...interface{}) []*Node {
+	t.Helper()
+	nodes := make([]*Node, 0, len(fns))
+	for _, fn := range fns {
+		n, err := NewNode(fn)
+		if err != nil {
+			t.Fatalf("NewNode failed: %v", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func TestResolve(t *testing.T) {
+	nodes := mustNodes(t,
+		func(*b) *c { return nil },
+		func(*a) *b { return nil },
+		func() *a { return nil },
+	)
+
+	sorted, err := Resolve(nodes)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if sorted[0] != nodes[2] || sorted[1] != nodes[1] || sorted[2] != nodes[0] {
+		t.Errorf("unexpected order: %v", sorted)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid Graph", func(t *testing.T) {
+		nodes := mustNodes(t,
+			func() *a { return nil },
+			func(*a) *b { return nil },
+		)
+		if err := Validate(nodes); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+	})
+
+	t.Run("Reports Every Problem", func(t *testing.T) {
+		nodes := mustNodes(t,
+			func() *a { return nil },
+			func() *a { return nil }, // duplicate
+			func(*c) {},              // missing *c
+			func(*d, *c) {},          // missing *d and *c
+			func(*b) *b { return nil },
+		)
+
+		err := Validate(nodes)
+		if err == nil {
+			t.Fatal("expected errors, got nil")
+		}
+
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("want joined error, got %T", err)
+		}
+
+		var missing, duplicate, cycles int
+		for _, e := range joined.Unwrap() {
+			var me *MissingDependencyError
+			var de *DuplicateProviderError
+			var ce *CycleError
+			switch {
+			case errors.As(e, &me):
+				missing++
+			case errors.As(e, &de):
+				duplicate++
+				if de.First != nodes[0] || de.Second != nodes[1] {
+					t.Errorf("unexpected duplicate pair: %v", de)
+				}
+			case errors.As(e, &ce):
+				cycles++
+			}
+		}
+		if missing != 3 || duplicate != 1 || cycles != 1 {
+			t.Errorf("want 3 missing, 1 duplicate, 1 cycle; got %d, %d, %d", missing, duplicate, cycles)
+		}
+	})
+
+	t.Run("Every Cycle", func(t *testing.T) {
+		nodes := mustNodes(t,
+			func(*b) *a { return nil },
+			func(*a) *b { return nil },
+			func(*d) *c { return nil },
+			func(*c) *d { return nil },
+		)
+
+		err := Validate(nodes)
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok || len(joined.Unwrap()) != 2 {
+			t.Fatalf("want 2 cycles, got %v", err)
+		}
+
+		var ce *CycleError
+		if !errors.As(joined.Unwrap()[0], &ce) {
+			t.Fatalf("want CycleError, got %v", joined.Unwrap()[0])
+		}
+		if len(ce.Component) != 2 || len(ce.Path) != 3 || ce.Path[0] != nodes[0] || ce.Path[1] != nodes[1] || ce.Path[2] != nodes[0] {
+			t.Errorf("unexpected cycle: path=%v component=%v", ce.Path, ce.Component)
+		}
+		if !errors.Is(err, ErrCycle) {
+			t.Error("errors.Is(err, ErrCycle) = false")
+		}
+	})
+}
+
+func TestDecorators(t *testing.T) {
+	t.Run("Chained In Registration Order", func(t *testing.T) {
+		nodes := mustNodes(t,
+			func(*a) {},                    // consumer
+			func(*a) *a { return nil },     // first decorator
+			func(*a, *b) *a { return nil }, // second decorator
+			func() *a { return nil },       // provider
+			func() *b { return nil },
+		)
+		nodes[1].Decorator = true
+		nodes[2].Decorator = true
+
+		g, err := Build(nodes)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if deps := g.Deps[nodes[1]]; len(deps) != 1 || deps[0] != nodes[3] {
+			t.Errorf("first decorator should depend on the provider, got %v", deps)
+		}
+		if deps := g.Deps[nodes[2]]; len(deps) != 2 || deps[0] != nodes[1] {
+			t.Errorf("second decorator should depend on the first, got %v", deps)
+		}
+		if deps := g.Deps[nodes[0]]; len(deps) != 1 || deps[0] != nodes[2] {
+			t.Errorf("consumer should depend on the last decorator, got %v", deps)
+		}
+	})
+
+	t.Run("Nothing To Decorate", func(t *testing.T) {
+		nodes := mustNodes(t, func(*a) *a { return nil })
+		nodes[0].Decorator = true
+
+		var missing *MissingDependencyError
+		if _, err := Resolve(nodes); !errors.As(err, &missing) || missing.Node != nodes[0] {
+			t.Fatalf("want missing dependency of the decorator, got %v", err)
+		}
+	})
+}
+
+func TestPrivateOutputs(t *testing.T) {
+	nodes := mustNodes(t,
+		func() *a { return nil },
+		func(*a) *b { return nil },
+		func(*a) *c { return nil },
+	)
+	nodes[0].Module = []string{"inner"}
+	nodes[0].Outputs[0].Module = "inner"
+	nodes[1].Module = []string{"inner", "outer"}
+	nodes[2].Module = []string{"outer"}
+
+	_, err := Resolve(nodes)
+	var private *PrivateProviderError
+	if !errors.As(err, &private) || private.Node != nodes[2] || private.Module != "inner" {
+		t.Fatalf("want private provider error for the outer node, got %v", err)
+	}
+
+	nodes = nodes[:2]
+	if _, err := Resolve(nodes); err != nil {
+		t.Fatalf("Resolve failed within the module: %v", err)
+	}
+
+	decorator := mustNodes(t, func(x *a) *a { return x })[0]
+	decorator.Decorator = true
+	decorator.Module = []string{"outer"}
+	_, err = Resolve(append(nodes, decorator))
+	if !errors.As(err, &private) || private.Node != decorator || private.Module != "inner" {
+		t.Fatalf("want private provider error for the outer decorator, got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	nodes := mustNodes(t,
+		func() *a { return nil },
+		func(*a, *c) *b { return nil },
+		func(*b) {},
+	)
+	nodes[2].Synthetic = true
+	nodes[2].Label = "populate"
+
+	dot := DOT(nodes, Validate(nodes))
+	for _, want := range []string{
+		`n0 -> n1 [label="*dag.a"];`,
+		`n2 [label="populate", style=dashed`,
+		`missing0 [label="missing *dag.c", shape=ellipse`,
+		`missing0 -> n1 [label="*dag.c", style=dashed`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output lacks %q:\n%s", want, dot)
+		}
+	}
+
+	mermaid := Mermaid(nodes, Validate(nodes))
+	for _, want := range []string{
+		`flowchart LR`,
+		`n1 -->|"*dag.b"| n2`,
+		`n2(["populate"])`,
+		`missing0 -.->|"*dag.c"| n1`,
+		`class n2 synthetic`,
+		`class missing0 missing`,
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output lacks %q:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	before := mustNodes(t,
+		func(*a) *b { return nil },
+		func() *a { return nil },
+	)
+	before[1].Module = []string{"inner", "outer"}
+
+	d, err := NewDump(before)
+	if err != nil {
+		t.Fatalf("NewDump failed: %v", err)
+	}
+	p := d.Providers[0]
+	if p.Order != 1 || d.Providers[1].Order != 0 {
+		t.Errorf("unexpected execution order %d and %d", p.Order, d.Providers[1].Order)
+	}
+	if p.ID != "graph_test.go func(*dag.a) *dag.b" || !strings.HasSuffix(p.File, "graph_test.go") || p.Line == 0 {
+		t.Errorf("unexpected ID %q or location %s:%d", p.ID, p.File, p.Line)
+	}
+	if !strings.HasPrefix(d.Providers[1].ID, "outer/inner: ") {
+		t.Errorf("want module path in ID, got %q", d.Providers[1].ID)
+	}
+	if len(d.Edges) != 1 || d.Edges[0].From != d.Providers[1].ID || d.Edges[0].Key != "*dag.a" {
+		t.Errorf("unexpected edges %v", d.Edges)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Dump
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := DiffDumps(d, &decoded); !diff.Empty() {
+		t.Errorf("want no difference after a round trip, got\n%s", diff)
+	}
+
+	after := append(before, mustNodes(t, func(*b) *c { return nil })...)
+	d2, err := NewDump(after)
+	if err != nil {
+		t.Fatalf("NewDump failed: %v", err)
+	}
+	diff := DiffDumps(d, d2)
+	if len(diff.AddedProviders) != 1 || len(diff.RemovedProviders) != 0 ||
+		len(diff.AddedEdges) != 1 || diff.AddedEdges[0].Key != "*dag.b" {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+	if reverse := DiffDumps(d2, d); len(reverse.RemovedProviders) != 1 || len(reverse.RemovedEdges) != 1 {
+		t.Errorf("unexpected reverse diff %+v", reverse)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	nodes := mustNodes(t,
+		func() *a { return nil },
+		func(*a) *b { return nil },
+		func(*b) *c { return nil },
+		func(*b) *b { return nil },
+	)
+	nodes[3].Decorator = true
+
+	if got := Producers(nodes, Key{Type: reflect.TypeOf((*b)(nil))}); len(got) != 2 || got[0] != nodes[1] || got[1] != nodes[3] {
+		t.Errorf("Producers = %v", got)
+	}
+	if got := Dependencies(nodes, nodes[2]); len(got) != 3 || got[0] != nodes[0] {
+		t.Errorf("Dependencies = %v", got)
+	}
+	if got := Dependents(nodes, nodes[0]); len(got) != 3 || got[0] != nodes[1] {
+		t.Errorf("Dependents = %v", got)
+	}
+}
@@ -5,12 +5,87 @@ import (
 	"reflect"
 )
 
+// Key identifies a value in the graph by its type and an optional name.
+// Two providers may produce the same type as long as their names differ.
+//
+// A Key with a Group refers to a value group: producers output keys of the
+// element type T, while consumers request the group with a key of type []T.
+//
+// Module is only set on outputs that are private to a module. Inputs never set it:
+// an input of a node registered by that module resolves to the private output
+// before any public one.
+type Key struct {
+	Type   reflect.Type
+	Name   string
+	Group  string
+	Module string
+}
+
+// Elem returns the key under which members of the group k are produced.
+// It must only be called on group keys of slice type.
+func (k Key) Elem() Key {
+	return Key{Type: k.Type.Elem(), Group: k.Group}
+}
+
+func (k Key) String() string {
+	switch {
+	case k.Name != "":
+		return fmt.Sprintf("%v[name=%q]", k.Type, k.Name)
+	case k.Group != "":
+		return fmt.Sprintf("%v[group=%q]", k.Type, k.Group)
+	}
+	return fmt.Sprint(k.Type)
+}
+
+// module returns the module owning n, or an empty string outside modules.
+func (n *Node) module() string {
+	if len(n.Module) == 0 {
+		return ""
+	}
+	return n.Module[0]
+}
+
+// Input is a dependency of a node.
+type Input struct {
+	Key
+	// Optional inputs receive the zero value when no provider exists,
+	// but are still ordered after the provider when one does.
+	Optional bool
+}
+
+// Output is a value produced by a node.
+type Output struct {
+	Key
+	// Index is the position of the return value holding this output.
+	// Several outputs share an index when a value is exposed under more than one key.
+	Index int
+}
+
 // Node holds reflection information about a constructor.
 type Node struct {
 	Fn           reflect.Value
-	Inputs       []reflect.Type
-	Outputs      []reflect.Type
+	Inputs       []Input
+	Outputs      []Output
 	ErrorIndices []int // indices of return values that are errors
+	CleanupIndex int   // index of a trailing func() or func() error cleanup result, or -1
+	// Label overrides the label derived from Fn, for nodes whose Fn is a synthetic
+	// wrapper around the registered constructor.
+	Label string
+	// Decorator marks a node that replaces existing values instead of providing new ones.
+	// Each of its outputs must also be one of its inputs. The input receives the value
+	// from the original provider or the previous decorator of the same key, and every
+	// other consumer of the key is ordered after the last decorator.
+	Decorator bool
+	// File and Line locate the registered constructor or supplied value in the source,
+	// when known.
+	File string
+	Line int
+	// Synthetic marks nodes created by the container itself, such as target populators
+	// and struct injectors, rather than registered constructors.
+	Synthetic bool
+	// Module is the path of the module that registered the node followed by the
+	// modules including it, innermost first. It is empty outside modules.
+	Module []string
 }
 
 func NewNode(fn interface{}) (*Node, error) {
@@ -23,14 +98,16 @@ func NewNode(fn interface{}) (*Node, error) {
 
 	n := &Node{
 		Fn:           val,
-		Inputs:       make([]reflect.Type, 0),
-		Outputs:      make([]reflect.Type, 0),
+		Inputs:       make([]Input, 0),
+		Outputs:      make([]Output, 0),
 		ErrorIndices: make([]int, 0),
+		CleanupIndex: -1,
 	}
+	n.File, n.Line = FuncLocation(val)
 
 	// Analyze inputs
 	for i := 0; i < typ.NumIn(); i++ {
-		n.Inputs = append(n.Inputs, typ.In(i))
+		n.Inputs = append(n.Inputs, Input{Key: Key{Type: typ.In(i)}})
 	}
 
 	// Analyze outputs
@@ -41,8 +118,28 @@ func NewNode(fn interface{}) (*Node, error) {
 			n.ErrorIndices = append(n.ErrorIndices, i)
 			continue
 		}
-		n.Outputs = append(n.Outputs, outTyp)
+		n.Outputs = append(n.Outputs, Output{Key: Key{Type: outTyp}, Index: i})
+	}
+
+	// A trailing func() or func() error following at least one output is the
+	// cleanup hook of the constructed resource, not a produced value.
+	if k := len(n.Outputs); k > 1 && isCleanupFunc(n.Outputs[k-1].Type) {
+		n.CleanupIndex = n.Outputs[k-1].Index
+		n.Outputs = n.Outputs[:k-1]
 	}
 
 	return n, nil
 }
+
+func isCleanupFunc(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 0 || t.IsVariadic() {
+		return false
+	}
+	switch t.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return t.Out(0) == reflect.TypeOf((*error)(nil)).Elem()
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package dag
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Producers returns the nodes producing what an input of key receives: its provider
+// followed by its decorators in chain order, or every member of a value group when
+// key is a group key of slice type. Values private to a module are only found by
+// keys of that module.
+func Producers(nodes []*Node, key Key) []*Node {
+	if key.Group != "" {
+		if key.Type.Kind() != reflect.Slice {
+			return nil
+		}
+		key = key.Elem()
+	}
+
+	var producers, decorators []*Node
+	for _, n := range nodes {
+		for _, out := range n.Outputs {
+			if out.Key != key {
+				continue
+			}
+			if n.Decorator {
+				decorators = append(decorators, n)
+			} else {
+				producers = append(producers, n)
+			}
+			break
+		}
+	}
+	return append(producers, decorators...)
+}
+
+// Dependencies returns the nodes that any of start depends on, directly or
+// transitively, in the order of nodes. Problems in the graph are ignored.
+func Dependencies(nodes []*Node, start ...*Node) []*Node {
+	deps, _, _ := buildDeps(nodes, true)
+	return reachable(nodes, start, deps)
+}
+
+// Dependents returns the nodes depending on any of start, directly or
+// transitively, in the order of nodes. Problems in the graph are ignored.
+func Dependents(nodes []*Node, start ...*Node) []*Node {
+	deps, _, _ := buildDeps(nodes, true)
+	dependents := make(map[*Node][]*Node)
+	for n, ms := range deps {
+		for _, m := range ms {
+			dependents[m] = append(dependents[m], n)
+		}
+	}
+	return reachable(nodes, start, dependents)
+}
+
+// reachable returns the nodes reachable from start through next, excluding start
+// itself, in the order of nodes.
+func reachable(nodes []*Node, start []*Node, next map[*Node][]*Node) []*Node {
+	seen := make(map[*Node]bool)
+	queue := slices.Clone(start)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, m := range next[n] {
+			if !seen[m] {
+				seen[m] = true
+				queue = append(queue, m)
+			}
+		}
+	}
+
+	var result []*Node
+	for _, n := range nodes {
+		if seen[n] && !slices.Contains(start, n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Decorate registers functions that wrap values provided elsewhere. A decorator takes
+// the current value of a type, plus any other dependencies, and returns its replacement:
+//
+//	b.Decorate(func(h http.Handler, m *Metrics) http.Handler {
+//		return m.Instrument(h)
+//	})
+//
+// Every consumer of the type receives the decorated value. Several decorators of the
+// same type are chained in registration order, each receiving the result of the previous
+// one. Decorators may return errors and cleanup functions like constructors do.
+//
+// Wrap a decorator with Annotate to decorate a named value: Name applies to the outputs
+// and to the untagged parameter of the same type. ParamTags select other dependencies.
+// Group, Optional, As, Private and Shadow are not supported.
+func (b *Bootstrap) Decorate(decorators ...interface{}) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b
+	}
+
+	for _, d := range decorators {
+		if err := b.registerDecorator(d); err != nil {
+			b.err = &RegistrationError{Arg: d, Err: err}
+			return b
+		}
+	}
+	return b
+}
+
+func (b *Bootstrap) registerDecorator(fn interface{}) error {
+	var opts options
+	if a, ok := fn.(Annotated); ok {
+		opts.apply(a.Options)
+		fn = a.Target
+	}
+
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func {
+		return fmt.Errorf("decorator must be a function")
+	}
+	if opts.group != "" || opts.optional || len(opts.as) > 0 || opts.private || opts.shadow {
+		return fmt.Errorf("options Group, Optional, As, Private and Shadow are not supported for decorator %v", val.Type())
+	}
+
+	p, err := providerNode(val, &opts)
+	if err != nil {
+		return err
+	}
+	if len(p.Outputs) == 0 {
+		return fmt.Errorf("decorator %v does not return a value", val.Type())
+	}
+	for _, out := range p.Outputs {
+		if out.Group != "" {
+			return fmt.Errorf("decorator %v cannot decorate value group %q", val.Type(), out.Group)
+		}
+		if !decorates(p, out.Key) {
+			return fmt.Errorf("decorator %v must take the %v it returns", val.Type(), out.Key)
+		}
+	}
+
+	p.Decorator = true
+	b.appendNode(p)
+	return nil
+}
+
+// decorates reports whether p takes the value it returns under key. An input of the
+// same type without name or group is bound to a named key.
+func decorates(p *dag.Node, key dag.Key) bool {
+	for _, in := range p.Inputs {
+		if in.Key == key {
+			return true
+		}
+	}
+	for i, in := range p.Inputs {
+		if in.Type == key.Type && in.Name == "" && in.Group == "" {
+			p.Inputs[i].Name = key.Name
+			return true
+		}
+	}
+	return false
+}
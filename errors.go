@@ -0,0 +1,78 @@
+package bootstrap
+
+import (
+	"fmt"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Graph errors returned by Run. They can be inspected with errors.As,
+// or matched with errors.Is against the sentinel values below.
+type (
+	MissingDependencyError = dag.MissingDependencyError
+	DuplicateProviderError = dag.DuplicateProviderError
+	CycleError             = dag.CycleError
+	PrivateProviderError   = dag.PrivateProviderError
+)
+
+var (
+	ErrMissingDependency = dag.ErrMissingDependency
+	ErrDuplicateProvider = dag.ErrDuplicateProvider
+	ErrCycle             = dag.ErrCycle
+	ErrPrivateProvider   = dag.ErrPrivateProvider
+)
+
+// RegistrationError reports an argument rejected by Add.
+type RegistrationError struct {
+	Arg interface{}
+	Err error
+}
+
+func (e *RegistrationError) Error() string {
+	return fmt.Sprintf("invalid registration: %v", e.Err)
+}
+
+func (e *RegistrationError) Unwrap() error {
+	return e.Err
+}
+
+// ConstructorError reports a constructor that returned an error or panicked during Run.
+// Provider is the label of the failing constructor. Stack holds the stack trace of a
+// panic, and is included in the message.
+type ConstructorError struct {
+	Provider string
+	Err      error
+	Stack    []byte
+	node     *dag.Node
+}
+
+func (e *ConstructorError) Error() string {
+	if len(e.Stack) > 0 {
+		return fmt.Sprintf("constructor %s failed: %v\n\n%s", e.Provider, e.Err, e.Stack)
+	}
+	return fmt.Sprintf("constructor %s failed: %v", e.Provider, e.Err)
+}
+
+func (e *ConstructorError) Unwrap() error {
+	return e.Err
+}
+
+// FailedNode returns the node of the failing constructor, implementing dag.NodeError.
+func (e *ConstructorError) FailedNode() *dag.Node {
+	return e.node
+}
+
+// CleanupError reports a component whose cleanup failed or did not finish in time.
+// A timed out cleanup wraps context.DeadlineExceeded or context.Canceled.
+type CleanupError struct {
+	Component string
+	Err       error
+}
+
+func (e *CleanupError) Error() string {
+	return fmt.Sprintf("cleanup %s: %v", e.Component, e.Err)
+}
+
+func (e *CleanupError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,69 @@
+package bootstrap
+
+import (
+	"errors"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// DOT renders the dependency graph in the Graphviz DOT language, for example to
+// document how an application starts up:
+//
+//	os.WriteFile("startup.dot", []byte(app.DOT()), 0o644)
+//
+// Nodes are labelled like constructors in error messages and edges with the type they
+// carry. Target populators, struct injectors and values inherited from a parent are
+// dashed. Every problem Validate would report and the constructor that made the last
+// Run fail are highlighted in red. See dag.DOT.
+func (b *Bootstrap) DOT() string {
+	return b.export(dag.DOT)
+}
+
+// Mermaid renders the dependency graph as a Mermaid flowchart, with the same
+// conventions as DOT. See dag.Mermaid.
+func (b *Bootstrap) Mermaid() string {
+	return b.export(dag.Mermaid)
+}
+
+func (b *Bootstrap) export(render func([]*dag.Node, error) string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Draw the nodes the last Run failed on, so that its error refers to them.
+	if b.runErr != nil && b.ran != nil {
+		return render(b.ran, errors.Join(dag.Validate(b.ran), b.runErr))
+	}
+	nodes, _, err := b.graphNodes()
+	if err != nil {
+		return render(b.providers, errors.Join(err, dag.Validate(b.providers)))
+	}
+	return render(nodes, dag.Validate(nodes))
+}
+
+// Dump describes the resolved graph for tooling, for example to detect in CI when a
+// change alters the startup graph:
+//
+//	dump, err := app.Dump()
+//	data, err := json.MarshalIndent(dump, "", "  ")
+//
+// Compare two dumps with dag.DiffDumps. In addition to dag.NewDump, a provider is marked
+// as cleaned up when the type of one of its outputs implements a cleanup method that
+// Bootstrap detects. Values inherited from a parent are listed as synthetic providers.
+func (b *Bootstrap) Dump() (*dag.Dump, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	nodes, inherited, err := b.graphNodes()
+	if err != nil {
+		return nil, err
+	}
+	d, err := dag.NewDump(nodes)
+	if err != nil {
+		return nil, err
+	}
+	b.markCleanups(d.Providers, nodes, inherited)
+	return d, nil
+}
@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// ErrValueNotFound is returned by Get when no value of the requested type has been built.
+var ErrValueNotFound = errors.New("value not found")
+
+// Provide registers constructor as the provider of T. Unlike Add, the signature is
+// checked by the compiler: a constructor that does not return T and an error does not
+// compile.
+//
+// The typed API covers constructors with up to three dependencies: Provide1, Provide2
+// and Provide3 take constructors with one to three, and the ProvideNoErr variants take
+// constructors that return T without an error. The coverage is intentionally partial;
+// use Add for other shapes, such as constructors with more dependencies, several results,
+// a cleanup function, or parameter and result objects.
+//
+//	bootstrap.Provide[*sql.DB](b, NewDB)
+func Provide[T any](b *Bootstrap, constructor func() (T, error), opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// Provide1 is Provide for a constructor with one dependency.
+//
+//	bootstrap.Provide1(b, NewRepository) // func(*sql.DB) (*Repository, error)
+func Provide1[T, A any](b *Bootstrap, constructor func(A) (T, error), opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// Provide2 is Provide for a constructor with two dependencies.
+func Provide2[T, A, B any](b *Bootstrap, constructor func(A, B) (T, error), opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// Provide3 is Provide for a constructor with three dependencies.
+func Provide3[T, A, B, C any](b *Bootstrap, constructor func(A, B, C) (T, error), opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// ProvideNoErr is Provide for a constructor that cannot fail.
+//
+//	bootstrap.ProvideNoErr(b, NewConfig) // func() *Config
+func ProvideNoErr[T any](b *Bootstrap, constructor func() T, opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// ProvideNoErr1 is ProvideNoErr for a constructor with one dependency.
+//
+//	bootstrap.ProvideNoErr1(b, NewDB) // func(*Config) *DB
+func ProvideNoErr1[T, A any](b *Bootstrap, constructor func(A) T, opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// ProvideNoErr2 is ProvideNoErr for a constructor with two dependencies.
+func ProvideNoErr2[T, A, B any](b *Bootstrap, constructor func(A, B) T, opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// ProvideNoErr3 is ProvideNoErr for a constructor with three dependencies.
+func ProvideNoErr3[T, A, B, C any](b *Bootstrap, constructor func(A, B, C) T, opts ...Option) *Bootstrap {
+	return b.Add(Annotate(constructor, opts...))
+}
+
+// Supply registers a ready-made value as the provider of T.
+//
+//	bootstrap.Supply[*Config](b, cfg)
+//	bootstrap.Supply[Store](b, pgStore) // registered under the interface type
+func Supply[T any](b *Bootstrap, value T, opts ...Option) *Bootstrap {
+	return b.supply(reflect.ValueOf(&value).Elem(), opts, callerLocation(1))
+}
+
+// Get returns the value of type T built by Run. Name or Group options select a named
+// value or a value group; for a group, T must be a slice of the member type.
+//
+//	db, err := bootstrap.Get[*sql.DB](b, bootstrap.Name("replica"))
+func Get[T any](b *Bootstrap, opts ...Option) (T, error) {
+	var zero T
+
+	var o options
+	o.apply(opts)
+	if err := o.validate(); err != nil {
+		return zero, err
+	}
+
+	key := dag.Key{Type: reflect.TypeOf((*T)(nil)).Elem(), Name: o.name, Group: o.group}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var v reflect.Value
+	if key.Group != "" {
+		if key.Type.Kind() != reflect.Slice {
+			return zero, fmt.Errorf("group %v must be requested as a slice", key)
+		}
+		v = b.groupValue(key)
+	} else if built, ok := b.values[key]; ok {
+		v = built
+	} else {
+		return zero, fmt.Errorf("%w: %v", ErrValueNotFound, key)
+	}
+
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return zero, nil
+	}
+	return v.Interface().(T), nil
+}
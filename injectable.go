@@ -6,3 +6,43 @@ type Inject struct {
 
 type injectable struct {
 }
+
+// In marks a parameter object: a struct passed by value to a constructor whose
+// exported fields are resolved as individual dependencies. Fields accept the same
+// name, group and optional tags as ParamTags.
+//
+//	type ServerParams struct {
+//		bootstrap.In
+//
+//		Config *Config
+//		DB     *sql.DB `name:"primary"`
+//		Tracer *Tracer `optional:"true"`
+//	}
+//
+//	func NewServer(p ServerParams) *Server
+type In struct {
+	_ parameterObject
+}
+
+type parameterObject struct {
+}
+
+// Out marks a result object: a struct returned by value from a constructor whose
+// exported fields are registered as individual outputs. Fields accept the name and
+// group tags.
+//
+//	type ModuleOut struct {
+//		bootstrap.Out
+//
+//		Client  *Client
+//		Metrics *Metrics
+//		Health  HealthCheck `group:"health"`
+//	}
+//
+//	func NewModule(cfg *Config) (ModuleOut, error)
+type Out struct {
+	_ resultObject
+}
+
+type resultObject struct {
+}
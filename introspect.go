@@ -0,0 +1,145 @@
+package bootstrap
+
+import (
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// BuiltValue is a value constructed by Run, as listed by Values.
+type BuiltValue struct {
+	// Key is the key the value is provided under. Group members have the element
+	// type of the group.
+	Key   dag.Key
+	Value interface{}
+	// Provider is the ID of the provider that produced the value, or of its last
+	// decorator, as in Providers.
+	Provider string
+}
+
+// Providers describes every registered provider in registration order, with its
+// inputs, outputs and source location. It is a read-only view meant for admin
+// endpoints and tests; see Dump for the edges between providers.
+func (b *Bootstrap) Providers() []dag.ProviderInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	nodes, inherited := b.introspectNodes()
+	return b.describe(nodes, inherited)
+}
+
+// Producers describes the providers of what a constructor parameter of type t, with the
+// Name or Group option given, would receive: the provider followed by its decorators,
+// or every member of the group, in which case t must be a slice type.
+func (b *Bootstrap) Producers(t reflect.Type, opts ...Option) []dag.ProviderInfo {
+	return b.query(t, opts, func(nodes []*dag.Node, producers ...*dag.Node) []*dag.Node {
+		return producers
+	})
+}
+
+// Dependencies describes the providers that the producers of t depend on, directly or
+// transitively. Options select a named value or a group as in Producers.
+func (b *Bootstrap) Dependencies(t reflect.Type, opts ...Option) []dag.ProviderInfo {
+	return b.query(t, opts, dag.Dependencies)
+}
+
+// Dependents describes the providers depending on the producers of t, directly or
+// transitively. Options select a named value or a group as in Producers.
+func (b *Bootstrap) Dependents(t reflect.Type, opts ...Option) []dag.ProviderInfo {
+	return b.query(t, opts, dag.Dependents)
+}
+
+// Values lists the values built by Run, in registration order of their providers.
+func (b *Bootstrap) Values() []BuiltValue {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	nodes := b.ran
+	if nodes == nil {
+		nodes = b.providers
+	}
+	infos := dag.Describe(nodes)
+	index := make(map[*dag.Node]int, len(nodes))
+	for i, p := range nodes {
+		index[p] = i
+	}
+
+	var values []BuiltValue
+	seen := make(map[dag.Key]bool)
+	for i, p := range nodes {
+		for _, out := range p.Outputs {
+			if out.Group != "" {
+				for _, v := range b.groups[out.Key][p] {
+					values = append(values, BuiltValue{Key: out.Key, Value: v.Interface(), Provider: infos[i].ID})
+				}
+				continue
+			}
+			v, ok := b.values[out.Key]
+			if !ok || seen[out.Key] {
+				continue
+			}
+			seen[out.Key] = true
+
+			// The value stored is the one of the last decorator, if any.
+			producers := dag.Producers(nodes, out.Key)
+			last := producers[len(producers)-1]
+			values = append(values, BuiltValue{Key: out.Key, Value: v.Interface(), Provider: infos[index[last]].ID})
+		}
+	}
+	return values
+}
+
+// query describes the nodes selected by fn from the producers of t.
+func (b *Bootstrap) query(t reflect.Type, opts []Option, fn func(nodes []*dag.Node, producers ...*dag.Node) []*dag.Node) []dag.ProviderInfo {
+	var o options
+	o.apply(opts)
+	key := dag.Key{Type: t, Name: o.name, Group: o.group}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	nodes, inherited := b.introspectNodes()
+	infos := b.describe(nodes, inherited)
+	index := make(map[*dag.Node]int, len(nodes))
+	for i, p := range nodes {
+		index[p] = i
+	}
+
+	selected := fn(nodes, dag.Producers(nodes, key)...)
+	result := make([]dag.ProviderInfo, 0, len(selected))
+	for _, p := range selected {
+		result = append(result, infos[index[p]])
+	}
+	return result
+}
+
+// introspectNodes returns the graph nodes Run would use, falling back to the
+// registered providers when they cannot be determined.
+func (b *Bootstrap) introspectNodes() ([]*dag.Node, map[*dag.Node]bool) {
+	nodes, inherited, err := b.graphNodes()
+	if err != nil {
+		return b.providers, nil
+	}
+	return nodes, inherited
+}
+
+// describe describes nodes like dag.Describe, additionally marking providers whose
+// outputs have a cleanup method Bootstrap detects.
+func (b *Bootstrap) describe(nodes []*dag.Node, inherited map[*dag.Node]bool) []dag.ProviderInfo {
+	infos := dag.Describe(nodes)
+	b.markCleanups(infos, nodes, inherited)
+	return infos
+}
+
+func (b *Bootstrap) markCleanups(infos []dag.ProviderInfo, nodes []*dag.Node, inherited map[*dag.Node]bool) {
+	for i, p := range nodes {
+		if inherited[p] {
+			continue
+		}
+		for _, out := range p.Outputs {
+			if cleanableType(out.Type, b.autoCleanup(out.Type)) {
+				infos[i].Cleanup = true
+			}
+		}
+	}
+}
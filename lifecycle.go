@@ -0,0 +1,144 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default timeouts applied to each start and stop hook.
+const (
+	DefaultStartTimeout = 15 * time.Second
+	DefaultStopTimeout  = 15 * time.Second
+)
+
+// Hook is a pair of callbacks run when the application starts and stops.
+// Either callback may be nil. Name is used in error messages.
+type Hook struct {
+	Name    string
+	OnStart func(context.Context) error
+	OnStop  func(context.Context) error
+}
+
+// Lifecycle lets constructors register hooks run by Bootstrap.Start and Bootstrap.Stop.
+// It is provided by the container and can be injected into any constructor.
+type Lifecycle interface {
+	Append(Hook)
+}
+
+// Startable is implemented by components that need to be started after
+// every component has been constructed.
+type Startable interface {
+	Start(ctx context.Context) error
+}
+
+// Stoppable is implemented by components that need to be stopped before cleanup.
+type Stoppable interface {
+	Stop(ctx context.Context) error
+}
+
+type lifecycle struct {
+	mu           sync.Mutex
+	hooks        []Hook
+	started      int // number of hooks whose OnStart succeeded
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+	}
+}
+
+// Append registers a hook. Hooks are appended as constructors run, so they are
+// ordered by dependency: a hook starts after the hooks of its dependencies.
+func (l *lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if h.Name == "" {
+		h.Name = fmt.Sprintf("hook #%d", len(l.hooks)+1)
+	}
+	l.hooks = append(l.hooks, h)
+}
+
+func (l *lifecycle) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.hooks)
+}
+
+// truncate discards the hooks appended after the first n.
+func (l *lifecycle) truncate(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = l.hooks[:n]
+}
+
+// start runs the start hooks in order. If one fails, the hooks already
+// started are stopped in reverse order and both errors are returned.
+func (l *lifecycle) start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	for l.started < len(hooks) {
+		h := hooks[l.started]
+		if h.OnStart != nil {
+			if err := runWithTimeout(ctx, l.startTimeout, h.OnStart); err != nil {
+				err = fmt.Errorf("start %s: %w", h.Name, err)
+				return errors.Join(err, l.stop(ctx))
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// stop runs the stop hooks of started hooks in reverse order, continuing past failures.
+func (l *lifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := hooks[l.started-1]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := runWithTimeout(ctx, l.stopTimeout, h.OnStop); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runWithTimeout calls fn with a context bounded by timeout and returns as soon as
+// the context is done, even if fn ignores it and keeps running.
+func runWithTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Prefer the result of a function that finished at the same time.
+		select {
+		case err := <-done:
+			return err
+		default:
+			return ctx.Err()
+		}
+	}
+}
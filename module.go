@@ -0,0 +1,163 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Module bundles related registrations under a name so that libraries can export
+// them as a single value. A Module is accepted by Add like a constructor:
+//
+//	var Module = bootstrap.Module{
+//		Name:      "cache",
+//		Providers: []interface{}{NewRedis, NewClient},
+//	}
+//
+//	app.Add(cache.Module, api.Module)
+//
+// A module is registered once per Bootstrap, even when it is included several times
+// through different modules. Modules are identified by name, and including two
+// different modules with the same name is an error. Every node registered by
+// a module records the module path, which appears in error messages such as
+// "missing dependency *Redis needed by cache.NewClient (module: cache ← api)".
+type Module struct {
+	// Name identifies the module. It must not be empty.
+	Name string
+	// Providers holds anything Add accepts: constructors, annotated constructors,
+	// targets and struct injectors.
+	Providers []interface{}
+	// Modules are included after the providers.
+	Modules []Module
+	// Invokes are functions run for their side effects once their dependencies are
+	// built, such as registering routes. They may only return errors.
+	Invokes []interface{}
+}
+
+// modulePath formats a module path, innermost module first.
+func modulePath(path []string) string {
+	return strings.Join(path, " ← ")
+}
+
+func (b *Bootstrap) addModule(m Module) error {
+	if m.Name == "" {
+		return fmt.Errorf("module must have a name")
+	}
+	if prev, ok := b.modules[m.Name]; ok {
+		if !sameModule(prev, m) {
+			return fmt.Errorf("module %q registered twice with different contents", m.Name)
+		}
+		return nil // Already included
+	}
+	b.modules[m.Name] = m
+
+	parent := b.module
+	b.module = append([]string{m.Name}, parent...)
+	defer func() { b.module = parent }()
+
+	for _, p := range m.Providers {
+		if err := b.add(p); err != nil {
+			return fmt.Errorf("module %s: %w", modulePath(b.module), err)
+		}
+	}
+	for _, sub := range m.Modules {
+		if err := b.addModule(sub); err != nil {
+			return err
+		}
+	}
+	for _, fn := range m.Invokes {
+		if err := b.addInvoke(fn); err != nil {
+			return fmt.Errorf("module %s: %w", modulePath(b.module), err)
+		}
+	}
+	return nil
+}
+
+// sameModule reports whether a and b register the same constructors, modules and
+// invokes, for example when a function building a module is called twice.
+func sameModule(a, b Module) bool {
+	if a.Name != b.Name || len(a.Modules) != len(b.Modules) {
+		return false
+	}
+	if !sameArgs(a.Providers, b.Providers) || !sameArgs(a.Invokes, b.Invokes) {
+		return false
+	}
+	for i := range a.Modules {
+		if !sameModule(a.Modules[i], b.Modules[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameArgs(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameArg(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameArg compares functions by code pointer, annotated arguments by target and the
+// options they set, and anything else with reflect.DeepEqual.
+func sameArg(a, b interface{}) bool {
+	if aa, ok := a.(Annotated); ok {
+		ab, ok := b.(Annotated)
+		if !ok || !sameArg(aa.Target, ab.Target) {
+			return false
+		}
+		var oa, ob options
+		oa.apply(aa.Options)
+		ob.apply(ab.Options)
+		return reflect.DeepEqual(oa, ob)
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Func && vb.Kind() == reflect.Func {
+		return va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (b *Bootstrap) addInvoke(fn interface{}) error {
+	target := fn
+	if a, ok := fn.(Annotated); ok {
+		target = a.Target
+	}
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Func {
+		return fmt.Errorf("invoke must be a function")
+	}
+	for i := 0; i < typ.NumOut(); i++ {
+		if !typ.Out(i).Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+			return fmt.Errorf("invoke %v must only return errors", typ)
+		}
+	}
+	return b.add(fn)
+}
+
+// appendNode registers p as part of the module currently being added.
+func (b *Bootstrap) appendNode(p *dag.Node) {
+	p.Module = b.module
+	b.providers = append(b.providers, p)
+}
+
+// makePrivate scopes the outputs of p to the module currently being added.
+func (b *Bootstrap) makePrivate(p *dag.Node) error {
+	if len(b.module) == 0 {
+		return fmt.Errorf("option Private is only supported for providers of a module")
+	}
+	for i := range p.Outputs {
+		if p.Outputs[i].Group != "" {
+			return fmt.Errorf("value group %q cannot be private", p.Outputs[i].Group)
+		}
+		p.Outputs[i].Module = b.module[0]
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// fieldSource locates a flattened value in an original parameter or result list:
+// the value at index itself, or its field when field is not negative.
+type fieldSource struct {
+	index int
+	field int
+}
+
+// expandParams resolves the inputs of constructor fn, applying positional tags and
+// expanding every parameter object into one input per exported field. When fn has
+// parameter objects it returns a wrapper taking the flattened inputs, otherwise fn itself.
+func expandParams(fn reflect.Value, tags []string) (reflect.Value, []dag.Input, error) {
+	typ := fn.Type()
+
+	var (
+		inputs   []dag.Input
+		sources  []fieldSource
+		expanded bool
+	)
+	for i := 0; i < typ.NumIn(); i++ {
+		t := typ.In(i)
+		tag := ""
+		if i < len(tags) {
+			tag = tags[i]
+		}
+
+		if !isParamObject(t) {
+			info, err := parseTag(reflect.StructTag(tag))
+			if err != nil {
+				return reflect.Value{}, nil, err
+			}
+			inputs = append(inputs, info.input(t))
+			sources = append(sources, fieldSource{index: i, field: -1})
+			continue
+		}
+
+		if tag != "" {
+			return reflect.Value{}, nil, fmt.Errorf("parameter object %v cannot be tagged; tag its fields instead", t)
+		}
+		expanded = true
+		for j := 0; j < t.NumField(); j++ {
+			field := t.Field(j)
+			if field.PkgPath != "" || (field.Anonymous && field.Type == reflect.TypeOf(In{})) {
+				continue
+			}
+			info, err := parseTag(field.Tag)
+			if err != nil {
+				return reflect.Value{}, nil, fmt.Errorf("field %s of %v: %w", field.Name, t, err)
+			}
+			inputs = append(inputs, info.input(field.Type))
+			sources = append(sources, fieldSource{index: i, field: j})
+		}
+	}
+
+	if !expanded {
+		return fn, inputs, nil
+	}
+
+	// Create synthetic function taking the flattened inputs and
+	// rebuilding the parameter objects before calling fn.
+	inTypes := make([]reflect.Type, len(inputs))
+	for i, in := range inputs {
+		inTypes[i] = in.Type
+	}
+	outTypes := make([]reflect.Type, typ.NumOut())
+	for i := range outTypes {
+		outTypes[i] = typ.Out(i)
+	}
+
+	wrapper := reflect.MakeFunc(reflect.FuncOf(inTypes, outTypes, false), func(args []reflect.Value) []reflect.Value {
+		params := make([]reflect.Value, typ.NumIn())
+		for i := range params {
+			if isParamObject(typ.In(i)) {
+				params[i] = reflect.New(typ.In(i)).Elem()
+			}
+		}
+		for i, arg := range args {
+			src := sources[i]
+			if src.field < 0 {
+				params[src.index] = arg
+			} else {
+				params[src.index].Field(src.field).Set(arg)
+			}
+		}
+		return fn.Call(params)
+	})
+	return wrapper, inputs, nil
+}
+
+// isParamObject reports whether t is a struct embedding In.
+func isParamObject(t reflect.Type) bool {
+	return embeds(t, reflect.TypeOf(In{}))
+}
+
+// embeds reports whether t is a struct embedding the marker type.
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type == marker {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Override records a provider registered with Replace and the providers it replaced.
+type Override struct {
+	// Provider is the label of the replacement constructor.
+	Provider string
+	// Keys are the values taken over by the replacement.
+	Keys []dag.Key
+	// Replaced holds the labels of the providers whose outputs were taken over,
+	// in registration order.
+	Replaced []string
+}
+
+type override struct {
+	arg  interface{}
+	node *dag.Node
+	Override
+	// orphans are the keys stripped from a replaced provider because they share its
+	// return value with a replaced output, but that no replacement provides.
+	orphans []orphan
+}
+
+type orphan struct {
+	key      dag.Key
+	provider string
+}
+
+// Replace registers constructors that take over the values of existing providers, for
+// example to swap a database for an in-memory fake in tests:
+//
+//	app := bootstrap.New().Add(production...)
+//	app.Replace(func() *Database { return fakeDatabase })
+//
+// Each output of a replacement removes the output with the same key from every other
+// provider, whether registered before or after the replacement, along with the other
+// keys bound to the same return value with As. Those must be taken over by a replacement
+// as well. A provider left without outputs is not invoked at all. Wrap a replacement with Annotate to replace a named
+// value, or a whole value group with Group. Run and Validate fail with a
+// RegistrationError when a replacement did not replace anything. Overrides reports what
+// was replaced.
+func (b *Bootstrap) Replace(constructors ...interface{}) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b
+	}
+
+	for _, c := range constructors {
+		if err := b.registerReplacement(c); err != nil {
+			b.err = &RegistrationError{Arg: c, Err: err}
+			return b
+		}
+	}
+	return b
+}
+
+// Overrides returns the replacements registered with Replace, in registration order.
+func (b *Bootstrap) Overrides() []Override {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	overrides := make([]Override, 0, len(b.overrides))
+	for _, o := range b.overrides {
+		o.Keys = append([]dag.Key(nil), o.Keys...)
+		o.Replaced = append([]string(nil), o.Replaced...)
+		overrides = append(overrides, o.Override)
+	}
+	return overrides
+}
+
+func (b *Bootstrap) registerReplacement(fn interface{}) error {
+	var opts options
+	if a, ok := fn.(Annotated); ok {
+		opts.apply(a.Options)
+		if err := opts.validate(); err != nil {
+			return err
+		}
+		fn = a.Target
+	}
+
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func {
+		return fmt.Errorf("replacement must be a function")
+	}
+	if opts.optional || opts.private || opts.shadow {
+		return fmt.Errorf("options Optional, Private and Shadow are not supported for replacement %v", val.Type())
+	}
+
+	p, err := providerNode(val, &opts)
+	if err != nil {
+		return err
+	}
+	if len(p.Outputs) == 0 {
+		return fmt.Errorf("replacement %v does not return a value", val.Type())
+	}
+
+	o := &override{arg: fn, node: p, Override: Override{Provider: p.String()}}
+	for _, out := range p.Outputs {
+		if prev := b.overrideOf(out.Key); prev != nil {
+			return fmt.Errorf("%v is already replaced by %s", out.Key, prev.Provider)
+		}
+		o.Keys = append(o.Keys, out.Key)
+	}
+
+	// Take over the keys earlier replacements left without a provider.
+	for _, prev := range b.overrides {
+		orphans := prev.orphans[:0]
+		for _, orphan := range prev.orphans {
+			if !slices.Contains(o.Keys, orphan.key) {
+				orphans = append(orphans, orphan)
+			} else if !slices.Contains(o.Replaced, orphan.provider) {
+				o.Replaced = append(o.Replaced, orphan.provider)
+			}
+		}
+		prev.orphans = orphans
+	}
+	b.overrides = append(b.overrides, o)
+
+	// Take over the outputs of providers registered so far. The replacement
+	// is inserted in place of the first of them to keep group order stable.
+	providers := b.providers[:0:0]
+	inserted := false
+	for _, n := range b.providers {
+		before := len(n.Outputs)
+		keep := b.strip(n)
+		if len(n.Outputs) < before && !inserted {
+			providers = append(providers, p)
+			inserted = true
+		}
+		if keep {
+			providers = append(providers, n)
+		}
+	}
+	if !inserted {
+		providers = append(providers, p)
+	}
+	b.providers = providers
+	return nil
+}
+
+// provide appends the provider p unless all of its outputs are replaced.
+func (b *Bootstrap) provide(p *dag.Node) {
+	if b.strip(p) {
+		b.appendNode(p)
+	}
+}
+
+// strip removes the outputs of p taken over by a replacement and reports whether p
+// still needs to run. Outputs sharing a return value with a replaced output are removed
+// as well, so that no consumer receives the original value. If p still runs, the results
+// it no longer provides are cleaned up by store. Decorators and replacements themselves
+// are left untouched.
+func (b *Bootstrap) strip(p *dag.Node) bool {
+	if p.Decorator || len(p.Outputs) == 0 || b.isReplacement(p) {
+		return true
+	}
+
+	replaced := make(map[int]*override)
+	for _, out := range p.Outputs {
+		if o := b.overrideOf(out.Key); o != nil {
+			replaced[out.Index] = o
+		}
+	}
+	if len(replaced) == 0 {
+		return true
+	}
+
+	label := p.String()
+	outputs := p.Outputs[:0]
+	for _, out := range p.Outputs {
+		o, ok := replaced[out.Index]
+		if !ok {
+			outputs = append(outputs, out)
+			continue
+		}
+		if !slices.Contains(b.replaced[p], out.Index) {
+			b.replaced[p] = append(b.replaced[p], out.Index)
+		}
+		if own := b.overrideOf(out.Key); own != nil {
+			o = own
+		} else {
+			o.orphans = append(o.orphans, orphan{key: out.Key, provider: label})
+			continue
+		}
+		if !slices.Contains(o.Replaced, label) {
+			o.Replaced = append(o.Replaced, label)
+		}
+	}
+	p.Outputs = outputs
+	return len(outputs) > 0
+}
+
+func (b *Bootstrap) isReplacement(p *dag.Node) bool {
+	for _, o := range b.overrides {
+		if o.node == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bootstrap) overrideOf(key dag.Key) *override {
+	for _, o := range b.overrides {
+		for _, k := range o.Keys {
+			if k == key {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// checkOverrides reports replacements that did not replace any provider, or that left
+// values bound to a replaced return value without a provider.
+func (b *Bootstrap) checkOverrides() error {
+	for _, o := range b.overrides {
+		if len(o.Replaced) == 0 {
+			keys := make([]string, len(o.Keys))
+			for i, k := range o.Keys {
+				keys[i] = k.String()
+			}
+			return &RegistrationError{
+				Arg: o.arg,
+				Err: fmt.Errorf("replacement %s did not replace anything: no provider of %s", o.Provider, strings.Join(keys, ", ")),
+			}
+		}
+		if len(o.orphans) > 0 {
+			orphan := o.orphans[0]
+			return &RegistrationError{
+				Arg: o.arg,
+				Err: fmt.Errorf("replacement %s takes over %s, which also provides %v: replace %v as well, for example with As",
+					o.Provider, orphan.provider, orphan.key, orphan.key),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// expandResults expands every result object returned by constructor fn into one
+// result per exported field. It returns a wrapper returning the flattened results
+// together with the tags of each flattened result by index, or fn itself when it
+// returns no result object.
+func expandResults(fn reflect.Value) (reflect.Value, map[int]tagInfo, error) {
+	typ := fn.Type()
+
+	var (
+		outTypes []reflect.Type
+		sources  []fieldSource
+		tags     = make(map[int]tagInfo)
+		expanded bool
+	)
+	for i := 0; i < typ.NumOut(); i++ {
+		t := typ.Out(i)
+		if !isResultObject(t) {
+			outTypes = append(outTypes, t)
+			sources = append(sources, fieldSource{index: i, field: -1})
+			continue
+		}
+
+		expanded = true
+		for j := 0; j < t.NumField(); j++ {
+			field := t.Field(j)
+			if field.PkgPath != "" || (field.Anonymous && field.Type == reflect.TypeOf(Out{})) {
+				continue
+			}
+			info, err := parseTag(field.Tag)
+			if err != nil {
+				return reflect.Value{}, nil, fmt.Errorf("field %s of %v: %w", field.Name, t, err)
+			}
+			if info.optional {
+				return reflect.Value{}, nil, fmt.Errorf("field %s of %v: optional tag is not supported for results", field.Name, t)
+			}
+			tags[len(outTypes)] = info
+			outTypes = append(outTypes, field.Type)
+			sources = append(sources, fieldSource{index: i, field: j})
+		}
+	}
+
+	if !expanded {
+		return fn, nil, nil
+	}
+
+	inTypes := make([]reflect.Type, typ.NumIn())
+	for i := range inTypes {
+		inTypes[i] = typ.In(i)
+	}
+
+	// Create synthetic function returning the fields of the result objects
+	wrapper := reflect.MakeFunc(reflect.FuncOf(inTypes, outTypes, false), func(args []reflect.Value) []reflect.Value {
+		results := fn.Call(args)
+		out := make([]reflect.Value, len(sources))
+		for i, src := range sources {
+			if src.field < 0 {
+				out[i] = results[src.index]
+			} else {
+				out[i] = results[src.index].Field(src.field)
+			}
+		}
+		return out
+	})
+	return wrapper, tags, nil
+}
+
+// isResultObject reports whether t is a struct embedding Out.
+func isResultObject(t reflect.Type) bool {
+	return embeds(t, reflect.TypeOf(Out{}))
+}
@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/viilon/bootstrap/dag"
+)
+
+// Supply registers ready-made values, each under its own type, without wrapping them in
+// constructors. Wrap a value with Annotate to register it under a name, a group, or an
+// additional interface type with As. Supplied values take part in duplicate checks and
+// are cleaned up like constructed ones.
+//
+//	b.Supply(cfg, Annotate(pgStore, As[Store]()))
+func (b *Bootstrap) Supply(values ...interface{}) *Bootstrap {
+	at := callerLocation(1)
+	for _, value := range values {
+		var opts []Option
+		if a, ok := value.(Annotated); ok {
+			value, opts = a.Target, a.Options
+		}
+		if value == nil {
+			return b.fail(&RegistrationError{Arg: value, Err: fmt.Errorf("cannot supply untyped nil")})
+		}
+		b.supply(reflect.ValueOf(value), opts, at)
+	}
+	return b
+}
+
+// supply registers v with the given options; at is the location of the caller.
+func (b *Bootstrap) supply(v reflect.Value, opts []Option, at location) *Bootstrap {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b
+	}
+
+	var o options
+	o.apply(opts)
+	err := o.validate()
+	if err == nil {
+		err = b.registerSupply(v, &o, at)
+	}
+	if err != nil {
+		b.err = &RegistrationError{Arg: v.Interface(), Err: err}
+	}
+	return b
+}
+
+func (b *Bootstrap) registerSupply(v reflect.Value, opts *options, at location) error {
+	if len(opts.paramTags) > 0 || opts.optional || opts.private {
+		return fmt.Errorf("parameter tags, Optional and Private are not supported for supplied value %v", v.Type())
+	}
+
+	// Create synthetic function: func() T
+	fnType := reflect.FuncOf(nil, []reflect.Type{v.Type()}, false)
+	fn := reflect.MakeFunc(fnType, func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{v}
+	})
+
+	p, err := dag.NewNode(fn.Interface())
+	if err != nil {
+		return err
+	}
+	p.Label = fmt.Sprintf("supplied %v (%s)", v.Type(), at)
+	p.File, p.Line = at.file, at.line
+	for i := range p.Outputs {
+		p.Outputs[i].Name = opts.name
+		p.Outputs[i].Group = opts.group
+	}
+	if err := bindInterfaces(p, opts); err != nil {
+		return err
+	}
+	if opts.shadow {
+		b.shadowing[p] = true
+	}
+	b.provide(p)
+	return nil
+}
+
+// location is a position in the source code.
+type location struct {
+	file string
+	line int
+}
+
+func (l location) String() string {
+	if l.file == "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", l.file, l.line)
+}
+
+// callerLocation returns the location of the caller skip frames above its caller.
+func callerLocation(skip int) location {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return location{}
+	}
+	return location{file: file, line: line}
+}